@@ -0,0 +1,37 @@
+package components
+
+import (
+	. "github.com/pyros2097/gromer/gsx"
+)
+
+var LayoutStyles = M{
+	"container": "flex flex-col min-h-screen bg-gray-50",
+	"footer": M{
+		"container": "mt-16 p-4 flex flex-col",
+		"link":      "hover:underline",
+		"subtitle":  "m-0.5 text-xs text-center text-gray-500",
+	},
+}
+
+func Layout(c *Context) []*Tag {
+	c.AddMeta("author", "gromer")
+	return c.Render(`
+		<div class="Layout">
+			<Slot />
+			<footer class="footer">
+				<span class="subtitle">"Written by "
+					<a class="link" href="https://github.com/pyrossh/">"pyrossh"</a>
+				</span>
+				<span class="subtitle">"using " 
+					<a class="link" href="https://github.com/pyrossh/gromer">"Gromer"</a>	
+				</span>
+				<span class="subtitle">"thanks to" 
+					<a class="link" href="https://github.com/wishawa/">"Wisha Wa"</a>
+				</span>
+				<span class="subtitle">"according to the spec "
+					<a class="link" href="https://todomvc.com/">"TodoMVC"</a>
+				</span>	
+			</footer>
+		</div>
+	`)
+}
@@ -11,17 +11,10 @@ var TodoStyles = M{
 	"button-1":  "ml-4 text-gray-400",
 	"label":     "flex-1 min-w-0 flex items-center break-all ml-2 p-2 text-gray-800",
 	"striked":   "text-gray-500 line-through",
-	"button-2":  "mr-4 text-red-700 opacity-0 hover:opacity-100",
-	"unchecked": "text-gray-200",
+	"button-2":  "mr-4 text-red-700 opacity-0 group-hover:opacity-100",
 }
 
 func Todo(c *Context, todo *todos.Todo) []*Tag {
-	checked := "/icons/unchecked.svg?fill=gray-400"
-	if todo.Completed {
-		checked = "/icons/checked.svg?fill=green-500"
-	}
-	c.Set("checked", checked)
-	c.Styles(TodoStyles)
 	return c.Render(`
 		<div id="todo-{todo.ID}" class="Todo">
 			<div class="row">
@@ -29,7 +22,15 @@ func Todo(c *Context, todo *todos.Todo) []*Tag {
 					<input type="hidden" name="intent" value="complete" />
 					<input type="hidden" name="id" value={todo.ID} />
 					<button class="button-1">	
-						<img src={checked} width="24" height="24" />
+						if todo.Completed {
+							return (
+								<img src="/icons/checked.svg?fill=green-500" width="24" height="24" />
+							)
+						} else {
+							return (
+								<img src="/icons/unchecked.svg?fill=gray-400" width="24" height="24" />
+							)
+						}
 					</button>
 				</form>
 				<label class={ "label": true, "striked": todo.Completed }>
@@ -8,7 +8,7 @@ func Error(c *Context, err error) []*Tag {
 	c.Set("err", err.Error())
 	return c.Render(`
 		<span class="Error">
-			<strong>Failed to load: {err}</strong>
+			<strong>"Failed to load: " {err}</strong>
 		</span>
 	`)
 }
@@ -21,7 +21,7 @@ func TodoCount(c *Context, filter string) []*Tag {
 	}
 	c.Set("count", count)
 	return c.Render(`
-		<span id="todo-count" class="TodoCount" hx-swap-oob="true">
+		<span id="todo-count" class="TodoCount">
 			<strong>{count}</strong>" item left"
 		</span>
 	`)
@@ -6,14 +6,18 @@ import (
 	. "github.com/pyros2097/gromer/gsx"
 )
 
-func TodoList(c *Context, page int, filter string) []*Tag {
+type TodoListProps struct {
+	Page   int    `json:"page" default:"1"`
+	Filter string `json:"filter" default:"all"`
+}
+
+func TodoList(c *Context, props TodoListProps) []*Tag {
 	// c.Styles(M{
 	// 	"container": "list-none",
 	// })
-	index := Default(page, 1)
 	todos, err := todos.GetAllTodo(c, todos.GetAllTodoParams{
-		Filter: filter,
-		Limit:  index,
+		Filter: props.Filter,
+		Limit:  Default(props.Page, 1),
 	})
 	if err != nil {
 		return Error(c, err)
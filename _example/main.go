@@ -9,12 +9,16 @@ import (
 	"github.com/pyros2097/gromer/gsx"
 )
 
+//go:generate go run github.com/pyros2097/gromer/cmd/gsx ./components ./containers ./routes
+
 func main() {
 	gsx.RegisterComponent(components.Todo, components.TodoStyles, "todo")
+	gsx.RegisterComponent(components.Layout, components.LayoutStyles)
 	gsx.RegisterComponent(components.Status, components.StatusStyles, "status", "error")
 	gsx.RegisterComponent(containers.TodoCount, nil, "filter")
-	gsx.RegisterComponent(containers.TodoList, nil, "page", "filter")
+	gsx.RegisterComponent(containers.TodoList, nil)
 	gromer.Init(components.Status, assets.FS)
+	gromer.Layout("/", components.Layout)
 	gromer.PageRoute("/", routes.TodosPage, routes.TodosAction)
 	gromer.PageRoute("/about", routes.AboutPage, nil)
 	gromer.Run("3000")
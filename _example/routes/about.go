@@ -14,8 +14,8 @@ func AboutPage(c *Context) ([]*Tag, int, error) {
 	})
 	return c.Render(`
 		<div class="About">
-			A new link is here
-			<h1>About Me</h1>
+			"A new link is here"
+			<h1>"About Me"</h1>
 		</div>
 	`), 200, nil
 }
@@ -10,7 +10,6 @@ import (
 var TodoMeta = M{
 	"title":       "Gromer Todos",
 	"description": "Gromer Todos",
-	"author":      "gromer",
 	"keywords":    "gromer",
 }
 
@@ -36,11 +35,6 @@ var TodoStyles = M{
 		"clear":     "font-light hover:underline",
 		"disabled":  "invisible disabled",
 	},
-	"footer": M{
-		"container": "mt-16 p-4 flex flex-col",
-		"link":      "hover:underline",
-		"subtitle":  "m-0.5 text-xs text-center text-gray-500",
-	},
 }
 
 type TodosPageParams struct {
@@ -48,20 +42,7 @@ type TodosPageParams struct {
 	Filter string `json:"filter"`
 }
 
-func getActive(v bool) string {
-	if v {
-		return "active"
-	}
-	return ""
-}
-
 func TodosPage(c *Context, params TodosPageParams) ([]*Tag, int, error) {
-	allClass := getActive(params.Filter == "all")
-	activeClass := getActive(params.Filter == "active")
-	completedClass := getActive(params.Filter == "completed")
-	c.Set("allClass", allClass)
-	c.Set("activeClass", activeClass)
-	c.Set("completedClass", completedClass)
 	c.Meta(TodoMeta)
 	c.Styles(TodoStyles)
 	return c.Render(`
@@ -74,7 +55,7 @@ func TodosPage(c *Context, params TodosPageParams) ([]*Tag, int, error) {
 					<div class="input-box">
 						<form hx-target="#todo-list" hx-post="/">
 							<input type="hidden" name="intent" value="select_all" />
-							<button id="check-all" class="button" hx-swap-oob="true">
+							<button id="check-all" class="button">
 								<img src="/icons/check-all.svg?fill=gray-400" />
 							</button>
 						</form>
@@ -83,20 +64,20 @@ func TodosPage(c *Context, params TodosPageParams) ([]*Tag, int, error) {
 							<input id="text" name="text" class="input" placeholder="What needs to be done?" autocomplete="off" />
 						</form>
 					</div>
-					<TodoList id="todo-list" page={params.Page} filter={params.Filter} />
+					<TodoList page={params.Page} filter={params.Filter} />
 					<div class="bottom">
 						<div class="section-1">
 							<TodoCount filter={params.Filter} />
 						</div>
 						<ul class="section-2" hx-boost="true">
 							<li>
-								<a href="?filter=all" class="link {allClass}">"All"</a>
+								<a href="?filter=all" class={"link": true, "active": params.Filter == "all"}>"All"</a>
 							</li>
 							<li>
-								<a href="?filter=active" class="link {activeClass}">"Active"</a>
+								<a href="?filter=active" class={"link": true, "active": params.Filter == "active"}>"Active"</a>
 							</li>
 							<li>
-								<a href="?filter=completed" class="link {completedClass}">"Completed"</a>
+								<a href="?filter=completed" class={"link": true, "active": params.Filter == "completed"}>"Completed"</a>
 							</li>
 						</ul>
 						<div class="section-3">
@@ -109,20 +90,6 @@ func TodosPage(c *Context, params TodosPageParams) ([]*Tag, int, error) {
 				</main>
 				<div id="error">
 				</div>
-				<footer class="footer">
-					<span class="subtitle">"Written by "
-						<a class="link" href="https://github.com/pyrossh/">"pyrossh"</a>
-					</span>
-					<span class="subtitle">"using " 
-						<a class="link" href="https://github.com/pyrossh/gromer">"Gromer"</a>	
-					</span>
-					<span class="subtitle">"thanks to" 
-						<a class="link" href="https://github.com/wishawa/">"Wisha Wa"</a>
-					</span>
-					<span class="subtitle">"according to the spec "
-						<a class="link" href="https://todomvc.com/">"TodoMVC"</a>
-					</span>	
-				</footer>
 			</div>
 		</div>
 	`), 200, nil
@@ -152,13 +119,14 @@ func TodosAction(c *Context, params TodosActionParams) ([]*Tag, int, error) {
 				return nil, 500, err
 			}
 		}
-		return c.Render(`
+		return append(c.Render(`
+			<TodoList filter="all" page="1" />
+		`), c.OOB("", "", c.Render(`
 			<TodoCount filter="all" page="1" />
-			<button id="check-all" class="button" hx-swap-oob="true">
+			<button id="check-all" class="button">
 				<img src="/icons/check-all.svg?fill=green-500" />
 			</button>
-			<TodoList id="todo-list" filter="all" page="1" />
-		`), 200, nil
+		`))...), 200, nil
 	} else if params.Intent == "clear_completed" {
 		allTodos, err := todos.GetAllTodo(c, todos.GetAllTodoParams{
 			Filter: "all",
@@ -175,20 +143,22 @@ func TodosAction(c *Context, params TodosActionParams) ([]*Tag, int, error) {
 				}
 			}
 		}
-		return c.Render(`
+		return append(c.Render(`
+			<TodoList filter="all" page="1" />
+		`), c.OOB("", "", c.Render(`
 			<TodoCount filter="all" page="1" />
-			<TodoList id="todo-list" filter="all" page="1" />
-		`), 200, nil
+		`))...), 200, nil
 	} else if params.Intent == "create" {
 		todo, err := todos.CreateTodo(c, params.Text)
 		if err != nil {
 			return nil, 500, err
 		}
 		c.Set("todo", todo)
-		return c.Render(`
-			<TodoCount filter="all" page="1" />
+		return append(c.Render(`
 			<Todo />
-		`), 200, nil
+		`), c.OOB("", "", c.Render(`
+			<TodoCount filter="all" page="1" />
+		`))...), 200, nil
 	} else if params.Intent == "delete" {
 		_, err := todos.DeleteTodo(c, params.ID)
 		if err != nil {
@@ -208,10 +178,11 @@ func TodosAction(c *Context, params TodosActionParams) ([]*Tag, int, error) {
 			return nil, 500, err
 		}
 		c.Set("todo", todo)
-		return c.Render(`
-			<TodoCount filter="all" page="1" />
+		return append(c.Render(`
 			<Todo />
-		`), 200, nil
+		`), c.OOB("", "", c.Render(`
+			<TodoCount filter="all" page="1" />
+		`))...), 200, nil
 	}
 	return nil, 404, eris.Errorf("Intent not specified: %s", params.Intent)
 }
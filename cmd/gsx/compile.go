@@ -0,0 +1,514 @@
+package main
+
+import (
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pyros2097/gromer/gsx"
+	"github.com/samber/lo"
+)
+
+var refRegex = regexp.MustCompile(`{(.*?)}`)
+
+func (c *compiler) tags(tags []*gsx.Tag) string {
+	s := "[]*gsx.Tag{\n"
+	for _, t := range tags {
+		s += c.tag(t) + ",\n"
+	}
+	return s + "}"
+}
+
+func (c *compiler) tag(t *gsx.Tag) string {
+	if t.Fragment {
+		return "gsx.NewFragment(" + c.tags(t.Children) + ")"
+	}
+	if t.Name == "" {
+		if t.Text.Str != nil {
+			return "gsx.NewText(" + strconv.Quote(*t.Text.Str) + ")"
+		} else if t.Text.Text != nil {
+			return "gsx.NewRawText(" + strings.Join(c.parts(*t.Text.Text, false), ", ") + ")"
+		} else if t.Text.Raw != nil {
+			return "gsx.NewRawText(gsx.Raw(" + strconv.Quote(*t.Text.Raw) + "))"
+		} else if t.Text.Ref != nil {
+			code, _ := c.ref(t.Text.Ref)
+			return "gsx.NewValue(" + code + ")"
+		} else if t.Text.For != nil {
+			return c.forLoop(t.Text.For)
+		} else if t.Text.If != nil {
+			return "gsx.NewFragment(func() []*gsx.Tag {\n" + c.ifStatement(t.Text.If) + "}())"
+		}
+		c.errorf("unsupported content in template")
+		return "nil"
+	}
+	if t.Name == "!--" {
+		return "gsx.NewComment(" + strconv.Quote(*t.Text.Raw) + ")"
+	} else if t.Name == "!DOCTYPE" {
+		return "gsx.NewDoctype(" + strconv.Quote(*t.Text.Raw) + ")"
+	}
+	if t.Name == "Slot" {
+		name := `""`
+		for _, a := range t.Attributes {
+			if a.Key != "name" || a.Value == nil {
+				continue
+			} else if a.Value.Str != nil {
+				name = strconv.Quote(removeQuotes(*a.Value.Str))
+			} else if a.Value.Ref != nil {
+				code, _ := c.ref(a.Value.Ref)
+				name = "fmt.Sprint(" + code + ")"
+				c.addImport("fmt", "fmt")
+			}
+		}
+		return "gsx.RenderSlot(c, " + name + ", func() []*gsx.Tag {\nreturn " + c.tags(t.Children) + "\n})"
+	}
+	if c.g.components[t.Name] {
+		props := "gsx.M{\n"
+		spreads := ""
+		for _, a := range t.Attributes {
+			if a.Spread != nil {
+				code, _ := c.ref(a.Spread)
+				spreads += ", " + code
+			} else if a.Value == nil {
+				props += strconv.Quote(a.Key) + ": true,\n"
+			} else if a.Value.Ref != nil {
+				code, _ := c.ref(a.Value.Ref)
+				props += strconv.Quote(a.Key) + ": " + code + ",\n"
+			} else if a.Value.Str != nil {
+				v := removeQuotes(*a.Value.Str)
+				if refRegex.MatchString(v) {
+					props += strconv.Quote(a.Key) + ": gsx.Concat(" + strings.Join(c.parts(v, false), ", ") + "),\n"
+				} else {
+					props += strconv.Quote(a.Key) + ": " + strconv.Quote(v) + ",\n"
+				}
+			}
+		}
+		props += "}"
+		if spreads != "" {
+			props = "gsx.SpreadProps(" + props + spreads + ")"
+		}
+		return "gsx.RenderComponent(c, " + strconv.Quote(t.Name) + ", " + props + ", " + c.tags(t.Children) + ")"
+	}
+	if unicode.IsUpper([]rune(t.Name)[0]) {
+		c.errorf("unknown component %s", t.Name)
+		return "nil"
+	}
+	attrs := "[]*gsx.Attribute{\n"
+	groups := []string{}
+	for _, a := range t.Attributes {
+		if a.Spread != nil {
+			code, _ := c.ref(a.Spread)
+			groups = append(groups, attrs+"}", "gsx.SpreadAttrs("+code+")")
+			attrs = "[]*gsx.Attribute{\n"
+			continue
+		}
+		attrs += c.attribute(a) + ",\n"
+	}
+	attrs += "}"
+	if len(groups) > 0 {
+		if attrs != "[]*gsx.Attribute{\n}" {
+			groups = append(groups, attrs)
+		}
+		attrs = "gsx.JoinAttrs(" + strings.Join(groups, ", ") + ")"
+	}
+	return "gsx.NewElement(" + strconv.Quote(t.Name) + ", " + strconv.FormatBool(t.SelfClosing) + ", " + attrs + ", " + c.tags(t.Children) + ")"
+}
+
+// parts splits the text into Raw parts and the code of the {refs} in it, quotes in the text of an
+// attribute are escaped.
+func (c *compiler) parts(v string, attr bool) []string {
+	parts := []string{}
+	last := 0
+	raw := func(s string) string {
+		if attr {
+			s = strings.ReplaceAll(s, `"`, "&#34;")
+		}
+		return "gsx.Raw(" + strconv.Quote(s) + ")"
+	}
+	for _, loc := range refRegex.FindAllStringSubmatchIndex(v, -1) {
+		parts = append(parts, raw(v[last:loc[0]]))
+		e, err := gsx.ParseExpr(v[loc[2]:loc[3]])
+		if err != nil {
+			c.errorf("invalid expression {%s}: %s", v[loc[2]:loc[3]], err)
+			return []string{"nil"}
+		}
+		code, _ := c.ref(e)
+		parts = append(parts, code)
+		last = loc[1]
+	}
+	if last < len(v) || last == 0 {
+		parts = append(parts, raw(v[last:]))
+	}
+	return parts
+}
+
+func (c *compiler) attribute(a *gsx.Attribute) string {
+	key := strconv.Quote(a.Key)
+	if a.Value == nil {
+		return "gsx.NewBoolAttr(" + key + ")"
+	} else if a.Value.Str != nil {
+		parts := c.parts(removeQuotes(*a.Value.Str), true)
+		return "gsx.NewAttr(" + key + ", " + strings.Join(parts, ", ") + ")"
+	} else if a.Value.Ref != nil {
+		code, _ := c.ref(a.Value.Ref)
+		return "gsx.NewAttr(" + key + ", " + code + ")"
+	} else if a.Key == "class" && a.Value.KV != nil {
+		names := []string{}
+		enabled := []string{}
+		for _, kv := range a.Value.KV {
+			names = append(names, strconv.Quote(removeQuotes(kv.Key)))
+			enabled = append(enabled, c.condition(kv.Value))
+		}
+		return "gsx.NewAttr(" + key + ", gsx.ClassNames([]string{" + strings.Join(names, ", ") + "}, []bool{" + strings.Join(enabled, ", ") + "}))"
+	}
+	return "gsx.NewAttr(" + key + ")"
+}
+
+func (c *compiler) forLoop(loop *gsx.ForStatement) string {
+	for _, name := range []string{loop.Index, loop.Key} {
+		if lo.Contains(reserved, name) {
+			c.errorf("%s can't be used as a loop variable", name)
+		}
+	}
+	statement := loop.Statements[0].ReturnStatement
+	empty := "nil"
+	if len(loop.Else) > 0 {
+		empty = "func(c *gsx.Context) []*gsx.Tag {\n" + c.statements(loop.Else) + "}"
+	}
+	code, typed := c.ref(loop.Reference)
+	// only slices are ranged in go as maps and the other types are iterated differently by gsx.
+	elem := c.sliceElem(loop.Reference)
+	typed = typed && elem != nil
+	vars := map[string]types.Type{loop.Index: nil}
+	if typed {
+		vars[loop.Index] = types.Typ[types.Int]
+	}
+	if loop.Key != "" {
+		vars[loop.Key] = elem
+	}
+	c.scopes = append(c.scopes, vars)
+	defer func() {
+		c.scopes = c.scopes[:len(c.scopes)-1]
+	}()
+	if !typed {
+		return "gsx.ForEach(c, " + code + ", " + strconv.Quote(loop.Index) + ", " + strconv.Quote(loop.Key) +
+			", func(c *gsx.Context) []*gsx.Tag {\nreturn " + c.tags(statement.Tags) + "\n}, " + empty + ")"
+	}
+	s := "gsx.NewFragment(func() []*gsx.Tag {\n"
+	if len(loop.Else) > 0 {
+		s += "if len(" + code + ") == 0 {\n" + c.statements(loop.Else) + "}\n"
+	}
+	s += "tags := []*gsx.Tag{}\n"
+	if loop.Key == "" {
+		s += "for " + loop.Index + " := range " + code + " {\n"
+	} else {
+		s += "for " + loop.Index + ", " + loop.Key + " := range " + code + " {\n"
+	}
+	s += "c := gsx.Scope(c)\n"
+	s += "c.Set(" + strconv.Quote(loop.Index) + ", " + loop.Index + ")\n"
+	if loop.Key != "" {
+		s += "c.Set(" + strconv.Quote(loop.Key) + ", " + loop.Key + ")\n"
+	}
+	return s + "tags = append(tags, " + c.tags(statement.Tags) + "...)\n" +
+		"}\n" +
+		"return tags\n" +
+		"}())"
+}
+
+// sliceElem returns the type of the items when the expression is a param with a slice or array type.
+func (c *compiler) sliceElem(e *gsx.Expr) types.Type {
+	p, not := postfix(e)
+	if p == nil || not || len(p.Suffixes) > 0 || p.Primary.Ident == nil {
+		return nil
+	}
+	_, t, _ := c.primary(p.Primary)
+	if t == nil {
+		return nil
+	}
+	switch it := t.Underlying().(type) {
+	case *types.Slice:
+		return it.Elem()
+	case *types.Array:
+		return it.Elem()
+	}
+	return nil
+}
+
+func (c *compiler) ifStatement(s *gsx.IfStatement) string {
+	code := "if " + c.condition(s.Condition) + " {\n" + c.statements(s.Statements) + "}"
+	if s.Else == nil {
+		return code + "\nreturn nil\n"
+	}
+	if s.Else.If != nil {
+		return code + " else " + c.ifStatement(s.Else.If)
+	}
+	return code + " else {\n" + c.statements(s.Else.Statements) + "}\n"
+}
+
+func (c *compiler) statements(statements []*gsx.Statement) string {
+	if len(statements) == 0 {
+		return "return nil\n"
+	}
+	return "return " + c.tags(statements[0].ReturnStatement.Tags) + "\n"
+}
+
+func (c *compiler) condition(e *gsx.Expr) string {
+	if p, not := postfix(e); p != nil && not {
+		code, _ := c.postfix(p)
+		return "!gsx.IsTruthy(" + code + ")"
+	}
+	code, _ := c.ref(e)
+	return "gsx.IsTruthy(" + code + ")"
+}
+
+// ref returns the go code for the expression and whether it is typed, typed expressions are
+// written as go expressions so that they are checked by the compiler, the other expressions are
+// evaluated with the context data at runtime.
+func (c *compiler) ref(e *gsx.Expr) (string, bool) {
+	if p, not := postfix(e); p != nil && !not {
+		return c.postfix(p)
+	}
+	c.check(e)
+	return "gsx.Ref(c, " + strconv.Quote(e.String()) + ")", false
+}
+
+func (c *compiler) postfix(p *gsx.PostfixExpr) (string, bool) {
+	used := lo.Assign(c.used)
+	if code, ok := c.selector(p); ok {
+		return code, true
+	}
+	// the params are read at runtime by gsx.Ref and are not used by the generated code.
+	c.used = used
+	c.checkPostfix(p)
+	return "gsx.Ref(c, " + strconv.Quote(p.String()) + ")", false
+}
+
+// selector returns the go code for the operand when its type is known, the fields and methods which don't
+// exist on the type are reported. Maps, interfaces and the operands whose type isn't known are read at runtime.
+func (c *compiler) selector(p *gsx.PostfixExpr) (string, bool) {
+	code, t, typed := c.primary(p.Primary)
+	if !typed {
+		return "", false
+	}
+	called := false
+	for i, suffix := range p.Suffixes {
+		if suffix.Field != nil {
+			if !isKnown(t) {
+				return "", false
+			}
+			name := *suffix.Field
+			obj, _, _ := types.LookupFieldOrMethod(t, true, c.p.types, name)
+			switch it := obj.(type) {
+			case *types.Var:
+				code += "." + name
+				t = it.Type()
+			case *types.Func:
+				// methods are called without parentheses like gsx does when they don't have any params.
+				sig := it.Type().(*types.Signature)
+				called = i+1 < len(p.Suffixes) && p.Suffixes[i+1].Call != nil
+				if !called && sig.Params().Len() > 0 {
+					c.errorf("method %s needs to be called with its arguments in {%s}", name, p.String())
+					return "", false
+				}
+				if sig.Results().Len() != 1 {
+					return "", false
+				}
+				code += "." + name
+				if !called {
+					code += "()"
+				}
+				t = sig.Results().At(0).Type()
+			default:
+				// the keys of maps and the values in interfaces are read at runtime like gsx does.
+				switch deref(t).Underlying().(type) {
+				case *types.Map, *types.Interface:
+					return "", false
+				}
+				c.errorf("%s has no field or method %s in {%s}", types.TypeString(t, types.RelativeTo(c.p.types)), name, p.String())
+				return "", false
+			}
+		} else if suffix.Index != nil {
+			index, typed := c.ref(suffix.Index)
+			if !typed {
+				return "", false
+			}
+			code += "[" + index + "]"
+			t = elemType(t)
+		} else if suffix.Call != nil {
+			args := []string{}
+			for _, a := range suffix.Call.Args {
+				arg, typed := c.ref(a)
+				if !typed {
+					return "", false
+				}
+				args = append(args, arg)
+			}
+			code += "(" + strings.Join(args, ", ") + ")"
+			// the type of a method call is its result which is already set.
+			if !called {
+				sig, ok := underlying(t).(*types.Signature)
+				t = nil
+				if ok && sig.Results().Len() == 1 {
+					t = sig.Results().At(0).Type()
+				}
+			}
+			called = false
+		}
+	}
+	return code, true
+}
+
+// primary returns the go code for the operand and its type when it is known.
+func (c *compiler) primary(p *gsx.Primary) (string, types.Type, bool) {
+	if p.Ident == nil {
+		if p.Sub != nil {
+			return "", nil, false
+		}
+		return p.String(), nil, true
+	}
+	name := *p.Ident
+	switch name {
+	case "true", "false", "nil":
+		return name, nil, true
+	}
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if t, ok := c.scopes[i][name]; ok {
+			return name, t, t != nil
+		}
+	}
+	if _, ok := c.params[name]; ok && !c.keys[name] {
+		c.used[name] = true
+		return name, c.types[name], true
+	}
+	return "", nil, false
+}
+
+func isKnown(t types.Type) bool {
+	return t != nil && t != types.Typ[types.Invalid]
+}
+
+func deref(t types.Type) types.Type {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+	return t
+}
+
+func underlying(t types.Type) types.Type {
+	if t == nil {
+		return nil
+	}
+	return t.Underlying()
+}
+
+// elemType returns the type of the items of slices, arrays and maps.
+func elemType(t types.Type) types.Type {
+	switch it := underlying(t).(type) {
+	case *types.Slice:
+		return it.Elem()
+	case *types.Array:
+		return it.Elem()
+	case *types.Map:
+		return it.Elem()
+	case *types.Pointer:
+		if arr, ok := it.Elem().Underlying().(*types.Array); ok {
+			return arr.Elem()
+		}
+	}
+	return nil
+}
+
+// check reports the identifiers in the expression which are not known in the template.
+func (c *compiler) check(e *gsx.Expr) {
+	for _, and := range append([]*gsx.AndExpr{e.Left}, e.Right...) {
+		for _, cmp := range append([]*gsx.CmpExpr{and.Left}, and.Right...) {
+			for _, add := range []*gsx.AddExpr{cmp.Left, cmp.Right} {
+				if add == nil {
+					continue
+				}
+				muls := []*gsx.MulExpr{add.Left}
+				for _, op := range add.Right {
+					muls = append(muls, op.Right)
+				}
+				for _, mul := range muls {
+					c.checkUnary(mul.Left)
+					for _, op := range mul.Right {
+						c.checkUnary(op.Right)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (c *compiler) checkUnary(u *gsx.UnaryExpr) {
+	if u.Not != nil {
+		c.checkUnary(u.Not)
+	} else if u.Neg != nil {
+		c.checkUnary(u.Neg)
+	} else {
+		c.checkPostfix(u.Postfix)
+	}
+}
+
+func (c *compiler) checkPostfix(p *gsx.PostfixExpr) {
+	// the fields and methods of the operands are checked even when the expression is read at runtime.
+	used := lo.Assign(c.used)
+	c.selector(p)
+	c.used = used
+	if p.Primary.Sub != nil {
+		c.check(p.Primary.Sub)
+	} else if name := p.Primary.Ident; name != nil && !c.known(*name) {
+		c.errorf("unknown reference %s", *name)
+	}
+	for _, suffix := range p.Suffixes {
+		if suffix.Index != nil {
+			c.check(suffix.Index)
+		} else if suffix.Call != nil {
+			for _, a := range suffix.Call.Args {
+				c.check(a)
+			}
+		}
+	}
+}
+
+func (c *compiler) known(name string) bool {
+	switch name {
+	case "true", "false", "nil", "len":
+		return true
+	}
+	for _, scope := range c.scopes {
+		if _, ok := scope[name]; ok {
+			return true
+		}
+	}
+	_, ok := c.params[name]
+	return ok || c.keys[name] || c.g.funcs[name]
+}
+
+// postfix returns the operand of an expression without any operators except a leading !.
+func postfix(e *gsx.Expr) (*gsx.PostfixExpr, bool) {
+	if len(e.Right) > 0 || len(e.Left.Right) > 0 {
+		return nil, false
+	}
+	cmp := e.Left.Left
+	if cmp.Right != nil || len(cmp.Left.Right) > 0 || len(cmp.Left.Left.Right) > 0 {
+		return nil, false
+	}
+	u := cmp.Left.Left.Left
+	if u.Postfix != nil {
+		return u.Postfix, false
+	}
+	if u.Not != nil && u.Not.Postfix != nil {
+		return u.Not.Postfix, true
+	}
+	return nil, false
+}
+
+func removeQuotes(s string) string {
+	if v, err := strconv.Unquote(s); err == nil {
+		return v
+	}
+	return strings.ReplaceAll(s, `"`, "")
+}
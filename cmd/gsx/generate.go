@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pyros2097/gromer/gsx"
+	"github.com/rotisserie/eris"
+)
+
+const gsxImport = "github.com/pyros2097/gromer/gsx"
+
+var (
+	// keys which are set in the context by gsx and gromer before a template is rendered.
+	builtinKeys = []string{"children", "slots", "props", "params", "funcName", "requestId"}
+	reserved    = []string{"c", "gsx", "tags"}
+)
+
+type pkg struct {
+	name    string
+	fset    *token.FileSet
+	files   map[string]*ast.File
+	decls   map[string]bool
+	structs map[string]*ast.StructType
+	types   *types.Package
+	info    *types.Info
+}
+
+type generator struct {
+	pkgs       []*pkg
+	components map[string]bool
+	funcs      map[string]bool
+	errs       []error
+}
+
+type template struct {
+	file     *ast.File
+	funcDecl *ast.FuncDecl
+	lit      *ast.BasicLit
+	pos      token.Position
+}
+
+// generate returns the contents of the generated files by path, a nil content means the file
+// has no templates anymore and should be removed.
+func generate(dirs []string) (map[string][]byte, error) {
+	g := &generator{
+		components: map[string]bool{},
+		funcs:      map[string]bool{},
+	}
+	fset := token.NewFileSet()
+	imp := exportImporter(fset, dirs)
+	for _, dir := range dirs {
+		p, err := loadPackage(dir, fset, imp)
+		if err != nil {
+			return nil, err
+		}
+		g.pkgs = append(g.pkgs, p)
+	}
+	for _, p := range g.pkgs {
+		for _, f := range p.files {
+			for _, d := range f.Decls {
+				if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil {
+					g.funcs[fd.Name.Name] = true
+					if isComponent(fd) {
+						g.components[fd.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+	files := map[string][]byte{}
+	for _, p := range g.pkgs {
+		names := []string{}
+		for name := range p.files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			data := g.generateFile(p, p.files[name])
+			if data != nil {
+				files[generatedName(name)] = data
+			} else if _, err := os.Stat(generatedName(name)); err == nil {
+				files[generatedName(name)] = nil
+			}
+		}
+	}
+	if len(g.errs) > 0 {
+		msgs := []string{}
+		for _, err := range g.errs {
+			msgs = append(msgs, err.Error())
+		}
+		return nil, eris.New(strings.Join(msgs, "\n"))
+	}
+	return files, nil
+}
+
+// exportImporter imports the packages from the export data of the packages imported by the dirs which is
+// built by go list like go/packages does.
+func exportImporter(fset *token.FileSet, dirs []string) types.Importer {
+	args := []string{"list", "-e", "-export", "-deps", "-f", "{{.ImportPath}}\t{{.Export}}"}
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) && !strings.HasPrefix(dir, ".") {
+			dir = "./" + dir
+		}
+		args = append(args, dir)
+	}
+	exports := map[string]string{}
+	out, _ := exec.Command("go", args...).Output()
+	for _, line := range strings.Split(string(out), "\n") {
+		if parts := strings.Split(line, "\t"); len(parts) == 2 && parts[1] != "" {
+			exports[parts[0]] = parts[1]
+		}
+	}
+	return importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		if file, ok := exports[path]; ok {
+			return os.Open(file)
+		}
+		return nil, eris.Errorf("no export data for %s", path)
+	})
+}
+
+func loadPackage(dir string, fset *token.FileSet, imp types.Importer) (*pkg, error) {
+	p := &pkg{
+		fset:    fset,
+		files:   map[string]*ast.File{},
+		decls:   map[string]bool{},
+		structs: map[string]*ast.StructType{},
+		info:    &types.Info{Defs: map[*ast.Ident]types.Object{}},
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range matches {
+		if isGenerated(name) || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(p.fset, name, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		p.name = f.Name.Name
+		p.files[name] = f
+		for _, d := range f.Decls {
+			switch it := d.(type) {
+			case *ast.FuncDecl:
+				if it.Recv == nil {
+					p.decls[it.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range it.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						p.decls[s.Name.Name] = true
+						if st, ok := s.Type.(*ast.StructType); ok {
+							p.structs[s.Name.Name] = st
+						}
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							p.decls[n.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	// the types of the params are checked so that the fields and methods in the templates can be checked,
+	// the errors are left to go build and the params whose types can't be resolved are read at runtime.
+	names := []string{}
+	for name := range p.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	files := []*ast.File{}
+	for _, name := range names {
+		files = append(files, p.files[name])
+	}
+	conf := types.Config{Importer: imp, Error: func(error) {}}
+	p.types, _ = conf.Check(dir, p.fset, files, p.info)
+	return p, nil
+}
+
+// isComponent reports whether the function looks like func(c *Context, ...) []*Tag.
+func isComponent(fd *ast.FuncDecl) bool {
+	params := fd.Type.Params.List
+	results := fd.Type.Results
+	if len(params) == 0 || results == nil || len(results.List) != 1 {
+		return false
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok || typeName(star.X) != "Context" {
+		return false
+	}
+	arr, ok := results.List[0].Type.(*ast.ArrayType)
+	if !ok {
+		return false
+	}
+	star, ok = arr.Elt.(*ast.StarExpr)
+	return ok && typeName(star.X) == "Tag"
+}
+
+func typeName(e ast.Expr) string {
+	switch it := e.(type) {
+	case *ast.Ident:
+		return it.Name
+	case *ast.SelectorExpr:
+		return it.Sel.Name
+	}
+	return ""
+}
+
+func findTemplates(p *pkg, f *ast.File) []*template {
+	templates := []*template{}
+	for _, d := range f.Decls {
+		fd, ok := d.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "Render" {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			templates = append(templates, &template{
+				file:     f,
+				funcDecl: fd,
+				lit:      lit,
+				pos:      p.fset.Position(lit.Pos()),
+			})
+			return true
+		})
+	}
+	return templates
+}
+
+func (g *generator) generateFile(p *pkg, f *ast.File) []byte {
+	templates := findTemplates(p, f)
+	if len(templates) == 0 {
+		return nil
+	}
+	imports := map[string]string{gsxImport: "gsx"}
+	body := &bytes.Buffer{}
+	counts := map[string]int{}
+	for _, t := range templates {
+		name := "gsx" + t.funcDecl.Name.Name
+		if counts[t.funcDecl.Name.Name] > 0 {
+			name += strconv.Itoa(counts[t.funcDecl.Name.Name])
+		}
+		counts[t.funcDecl.Name.Name]++
+		c := newCompiler(g, p, t, imports)
+		code := c.compile(name)
+		if len(c.errs) > 0 {
+			g.errs = append(g.errs, c.errs...)
+			continue
+		}
+		body.WriteString(code)
+	}
+	paths := []string{}
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	out := &bytes.Buffer{}
+	out.WriteString("// Code generated by gsx. DO NOT EDIT.\n\n")
+	out.WriteString("package " + p.name + "\n\n")
+	out.WriteString("import (\n")
+	for _, path := range paths {
+		out.WriteString("\t" + imports[path] + " " + strconv.Quote(path) + "\n")
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		g.errs = append(g.errs, eris.Wrapf(err, "failed to format generated code for %s", p.fset.Position(f.Pos()).Filename))
+		return nil
+	}
+	return src
+}
+
+type compiler struct {
+	g       *generator
+	p       *pkg
+	t       *template
+	imports map[string]string
+	params  map[string]ast.Expr
+	types   map[string]types.Type
+	keys    map[string]bool
+	used    map[string]bool
+	scopes  []map[string]types.Type
+	errs    []error
+}
+
+func newCompiler(g *generator, p *pkg, t *template, imports map[string]string) *compiler {
+	c := &compiler{
+		g:       g,
+		p:       p,
+		t:       t,
+		imports: imports,
+		params:  map[string]ast.Expr{},
+		types:   map[string]types.Type{},
+		keys:    map[string]bool{},
+		used:    map[string]bool{},
+	}
+	for _, k := range builtinKeys {
+		c.keys[k] = true
+	}
+	for i, field := range t.funcDecl.Type.Params.List {
+		if i == 0 {
+			continue
+		}
+		for _, n := range field.Names {
+			c.params[n.Name] = field.Type
+			if obj := p.info.Defs[n]; obj != nil && isKnown(obj.Type()) {
+				c.types[n.Name] = obj.Type()
+			}
+		}
+	}
+	// the props struct of a component is available in the template as props and its fields by their prop names.
+	if list := t.funcDecl.Type.Params.List; isComponent(t.funcDecl) && len(list) == 2 && len(list[1].Names) == 1 {
+		if ident, ok := list[1].Type.(*ast.Ident); ok && p.structs[ident.Name] != nil {
+			delete(c.params, list[1].Names[0].Name)
+			delete(c.keys, "props")
+			c.params["props"] = ident
+			c.types["props"] = c.types[list[1].Names[0].Name]
+			delete(c.types, list[1].Names[0].Name)
+			for _, field := range p.structs[ident.Name].Fields.List {
+				for _, n := range field.Names {
+					if name := propName(n.Name, field.Tag); n.IsExported() && name != "-" {
+						c.keys[name] = true
+					}
+				}
+			}
+		}
+	}
+	ast.Inspect(t.funcDecl.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Set" {
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				k, _ := strconv.Unquote(lit.Value)
+				c.keys[k] = true
+			}
+		}
+		return true
+	})
+	return c
+}
+
+func (c *compiler) errorf(format string, args ...interface{}) {
+	err := eris.Errorf("%s:%d: %s", c.t.pos.Filename, c.t.pos.Line, fmt.Sprintf(format, args...))
+	// an expression can be checked more than once when it falls back to being read at runtime.
+	for _, e := range c.errs {
+		if e.Error() == err.Error() {
+			return
+		}
+	}
+	c.errs = append(c.errs, err)
+}
+
+func (c *compiler) compile(name string) string {
+	tpl, err := strconv.Unquote(c.t.lit.Value)
+	if err != nil {
+		c.errorf("%s", err)
+		return ""
+	}
+	tags, err := gsx.Parse(fmt.Sprintf("%s:%d", c.t.pos.Filename, c.t.pos.Line), tpl)
+	if err != nil {
+		c.errs = append(c.errs, err)
+		return ""
+	}
+	code := c.tags(tags)
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "\nconst %sTemplate = %s\n\n", name, c.t.lit.Value)
+	fmt.Fprintf(out, "func init() {\n\tgsx.RegisterCompiled(%q, %sTemplate, %s)\n}\n\n", c.t.funcDecl.Name.Name, name, name)
+	fmt.Fprintf(out, "// %s is compiled from the template in %s:%d.\n", name, filepath.Base(c.t.pos.Filename), c.t.pos.Line)
+	fmt.Fprintf(out, "func %s(c *gsx.Context) []*gsx.Tag {\n", name)
+	params := []string{}
+	for p := range c.used {
+		params = append(params, p)
+	}
+	sort.Strings(params)
+	for _, p := range params {
+		fmt.Fprintf(out, "\t%s, ok := c.Get(%q).(%s)\n", p, p, c.typeString(c.params[p]))
+		fmt.Fprintf(out, "\tif !ok {\n\t\treturn c.Interpret(%sTemplate)\n\t}\n", name)
+	}
+	fmt.Fprintf(out, "\treturn %s\n}\n", code)
+	return out.String()
+}
+
+// typeString returns the type as it has to be written in the generated file, adding the imports it needs.
+func (c *compiler) typeString(e ast.Expr) string {
+	switch it := e.(type) {
+	case *ast.Ident:
+		if c.p.decls[it.Name] || isPredeclared(it.Name) {
+			return it.Name
+		}
+		dots := []*ast.ImportSpec{}
+		for _, spec := range c.t.file.Imports {
+			if spec.Name != nil && spec.Name.Name == "." {
+				dots = append(dots, spec)
+			}
+		}
+		if len(dots) != 1 {
+			c.errorf("could not find the package of type %s", it.Name)
+			return it.Name
+		}
+		path, _ := strconv.Unquote(dots[0].Path.Value)
+		return c.addImport(path, filepath.Base(path)) + "." + it.Name
+	case *ast.SelectorExpr:
+		x, ok := it.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		for _, spec := range c.t.file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			name := filepath.Base(path)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if name == x.Name {
+				return c.addImport(path, name) + "." + it.Sel.Name
+			}
+		}
+		c.errorf("could not find the import of type %s.%s", x.Name, it.Sel.Name)
+		return x.Name + "." + it.Sel.Name
+	case *ast.StarExpr:
+		return "*" + c.typeString(it.X)
+	case *ast.ArrayType:
+		if it.Len == nil {
+			return "[]" + c.typeString(it.Elt)
+		}
+	case *ast.MapType:
+		return "map[" + c.typeString(it.Key) + "]" + c.typeString(it.Value)
+	case *ast.InterfaceType:
+		if len(it.Methods.List) == 0 {
+			return "interface{}"
+		}
+	}
+	c.errorf("unsupported param type %T", e)
+	return "interface{}"
+}
+
+func (c *compiler) addImport(path, name string) string {
+	if path == gsxImport {
+		return "gsx"
+	}
+	if existing, ok := c.imports[path]; ok {
+		return existing
+	}
+	c.imports[path] = name
+	return name
+}
+
+// propName returns the name of the prop for the struct field like gsx does.
+func propName(field string, tag *ast.BasicLit) string {
+	name := ""
+	if tag != nil {
+		s, _ := strconv.Unquote(tag.Value)
+		name = strings.Split(reflect.StructTag(s).Get("json"), ",")[0]
+	}
+	if name == "" {
+		name = strings.ToLower(field[:1]) + field[1:]
+	}
+	return name
+}
+
+func isPredeclared(name string) bool {
+	switch name {
+	case "bool", "byte", "complex64", "complex128", "error", "float32", "float64", "int", "int8", "int16",
+		"int32", "int64", "rune", "string", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "any":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	r := require.New(t)
+	files, err := generate([]string{"testdata/todos"})
+	r.NoError(err)
+	r.Len(files, 1)
+	expected, err := os.ReadFile("testdata/todos/todos_gsx.golden")
+	r.NoError(err)
+	r.Equal(string(expected), string(files["testdata/todos/todos_gsx.go"]))
+}
+
+func TestGenerateErrors(t *testing.T) {
+	r := require.New(t)
+	_, err := generate([]string{"testdata/invalid"})
+	r.EqualError(err, strings.Join([]string{
+		"testdata/invalid/invalid.go:10: unknown reference nme",
+		"testdata/invalid/invalid.go:10: unknown reference lastName",
+		"testdata/invalid/invalid.go:10: unknown component Header",
+		"testdata/invalid/invalid.go:29: method Greet needs to be called with its arguments in {u.Greet}",
+		"testdata/invalid/invalid.go:29: *User has no field or method Nme in {u.Nme}",
+		"testdata/invalid/invalid.go:29: time.Time has no field or method Yer in {u.Joined.Yer}",
+		"testdata/invalid/invalid.go:29: *User has no field or method Titl in {u.Titl}",
+	}, "\n"))
+}
+
+func TestGenerateDynamic(t *testing.T) {
+	r := require.New(t)
+	files, err := generate([]string{"testdata/maps"})
+	r.NoError(err)
+	code := string(files["testdata/maps/maps_gsx.go"])
+	r.Contains(code, `gsx.NewAttr("title", gsx.Ref(c, "m.title"))`)
+	r.Contains(code, `gsx.NewValue(gsx.Ref(c, "v.Name"))`)
+	r.Contains(code, `gsx.NewValue(item.Title)`)
+	r.Contains(code, `gsx.NewValue(gsx.Ref(c, "item.Meta.label"))`)
+	r.Contains(code, `gsx.NewValue(item.Name())`)
+	r.Contains(code, `gsx.NewValue(item.Label("label: "))`)
+}
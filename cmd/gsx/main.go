@@ -0,0 +1,55 @@
+// Command gsx compiles the templates passed to c.Render in the go files of the given directories
+// into go render functions, so that typos in references and unknown components are reported when
+// they are compiled instead of failing when the page is rendered. The types of the params are checked
+// with the export data of the packages built by go list.
+//
+//	go run github.com/pyros2097/gromer/cmd/gsx ./components ./containers ./routes
+//
+// For every file with templates a file_gsx.go file is written next to it which registers the
+// compiled render functions with gsx.RegisterCompiled. When gsx.DevMode is set or a template was
+// changed without running the generator again the templates are interpreted at runtime.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	dirs := os.Args[1:]
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	files, err := generate(dirs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if files[name] == nil {
+			os.Remove(name)
+			continue
+		}
+		if err := os.WriteFile(name, files[name], 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("gsx: generated", filepath.ToSlash(name))
+	}
+}
+
+func isGenerated(filename string) bool {
+	return strings.HasSuffix(filename, "_gsx.go")
+}
+
+func generatedName(filename string) string {
+	return strings.TrimSuffix(filename, ".go") + "_gsx.go"
+}
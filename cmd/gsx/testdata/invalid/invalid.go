@@ -0,0 +1,30 @@
+package invalid
+
+import (
+	"time"
+
+	. "github.com/pyros2097/gromer/gsx"
+)
+
+func Page(c *Context, name string) []*Tag {
+	return c.Render(`
+		<div>
+			<span>{nme}</span>
+			<span>{name + " " + lastName}</span>
+			<Header title={name} />
+		</div>
+	`)
+}
+
+type User struct {
+	Name   string
+	Joined time.Time
+}
+
+func (u *User) Greet(greeting string) string {
+	return greeting + " " + u.Name
+}
+
+func Profile(c *Context, u *User) []*Tag {
+	return c.Render(`<span>{u.Greet}</span><span>{u.Nme}</span><span>{u.Joined.Yer}</span><span>{u.Name + u.Titl}</span>`)
+}
@@ -0,0 +1,30 @@
+package maps
+
+import (
+	. "github.com/pyros2097/gromer/gsx"
+)
+
+type Item struct {
+	Title string
+	Meta  M
+}
+
+func (i *Item) Name() string {
+	return "item " + i.Title
+}
+
+func (i *Item) Label(prefix string) string {
+	return prefix + i.Title
+}
+
+func Card(c *Context, m M, v interface{}, item *Item) []*Tag {
+	return c.Render(`
+		<div title={m.title}>
+			<span>{v.Name}</span>
+			<span>{item.Title}</span>
+			<span>{item.Meta.label}</span>
+			<span>{item.Name}</span>
+			<span>{item.Label("label: ")}</span>
+		</div>
+	`)
+}
@@ -0,0 +1,73 @@
+package todos
+
+import (
+	"time"
+
+	. "github.com/pyros2097/gromer/gsx"
+)
+
+type TodoData struct {
+	ID        string
+	Text      string
+	Completed bool
+	CreatedAt time.Time
+}
+
+func Todo(c *Context, todo *TodoData) []*Tag {
+	return c.Render(`
+		<li id="todo-{todo.ID}" class={"completed": todo.Completed }>
+			<input type="checkbox" checked={todo.Completed} disabled />
+			<span>{todo.Text}</span>
+			if !todo.Completed {
+				return (
+					<>
+						<a href="/todos/{todo.ID}" title="Edit {todo.Text} ({todo.ID})">Edit "{todo.Text}"</a>
+						<a href="/todos/{todo.ID}/complete">Complete</a>
+					</>
+				)
+			}
+			{children}
+			<Slot name="actions">
+				<button>"delete"</button>
+			</Slot>
+		</li>
+	`)
+}
+
+func TodoList(c *Context, todos []*TodoData, filter string) []*Tag {
+	c.Set("count", len(todos))
+	return c.Render(`
+		<!-- the list is swapped by htmx -->
+		<ul id="todo-list" class="relative">
+			for i, v := range todos {
+				return (
+					<Todo todo={v}>
+						<span>{i}</span>
+						<slot:actions>
+							<a href="/todos/{v.ID}/archive">"archive"</a>
+						</slot:actions>
+					</Todo>
+				)
+			} else {
+				return (
+					<li>No todos, add one above</li>
+				)
+			}
+		</ul>
+		<span>{count} "items"</span>
+		<a href="/todos" class={"selected": filter == "all"}>"All"</a>
+	`)
+}
+
+type TodoCountProps struct {
+	Count  int    `json:"count"`
+	Filter string `json:"filter" default:"all"`
+	Attrs  M      `gsx:"rest"`
+}
+
+func TodoCount(c *Context, p TodoCountProps) []*Tag {
+	return c.Render(`
+		<span class="todo-count" {...attrs}>{props.Count} " items " {filter}</span>
+		<textarea name="filter">{filter}</textarea>
+	`)
+}
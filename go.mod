@@ -18,6 +18,7 @@ require (
 	github.com/segmentio/go-camelcase v0.0.0-20160726192923-7085f1e3c734
 	github.com/stretchr/testify v1.7.1
 	gocloud.dev v0.24.0
+	gopkg.in/yaml.v3 v3.0.1
 	xojoc.pw/useragent v0.0.0-20200116211053-1ec61d55e8fe
 )
 
@@ -52,5 +53,4 @@ require (
 	google.golang.org/grpc v1.40.0 // indirect
 	google.golang.org/protobuf v1.27.1 // indirect
 	gopkg.in/square/go-jose.v2 v2.6.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
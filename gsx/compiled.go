@@ -0,0 +1,331 @@
+package gsx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/samber/lo"
+)
+
+var (
+	// DevMode makes Render always interpret the template string even when a compiled
+	// render function generated by cmd/gsx is registered for it.
+	DevMode           = false
+	compiledTemplates = map[string]func(c *Context) []*Tag{}
+)
+
+// RegisterCompiled registers a render function generated by cmd/gsx for the template of the component or page
+// function name. It is called from the init functions of the generated files.
+func RegisterCompiled(name, tpl string, f func(c *Context) []*Tag) {
+	compiledTemplates[tpl] = f
+	addTemplate(name, tpl)
+}
+
+// The functions below are used by the code generated by cmd/gsx to build the tags of a template,
+// they match what the interpreter does for the same template.
+
+func NewText(s string) *Tag {
+	return &Tag{Text: &Literal{Str: &s}}
+}
+
+func NewValue(v interface{}) *Tag {
+	if children, ok := v.([]*Tag); ok {
+		return NewFragment(children)
+	}
+	return NewRawText(v)
+}
+
+// NewRawText joins the parts of a text, Raw parts are written as is and the other parts are escaped.
+func NewRawText(parts ...interface{}) *Tag {
+	s := ""
+	for _, p := range parts {
+		s += escapeText(p)
+	}
+	return &Tag{Text: &Literal{Raw: &s}}
+}
+
+// Concat joins the parts of a component prop with {refs} in it into a string.
+func Concat(parts ...interface{}) string {
+	s := ""
+	for _, p := range parts {
+		if p != nil {
+			s += fmt.Sprint(p)
+		}
+	}
+	return s
+}
+
+func NewComment(s string) *Tag {
+	return &Tag{Name: "!--", Text: &Literal{Raw: &s}}
+}
+
+func NewDoctype(s string) *Tag {
+	return &Tag{Name: "!DOCTYPE", Text: &Literal{Raw: &s}}
+}
+
+func NewFragment(children []*Tag) *Tag {
+	return &Tag{Fragment: true, Children: children}
+}
+
+func NewElement(name string, selfClosing bool, attributes []*Attribute, children []*Tag) *Tag {
+	return &Tag{
+		Name:        name,
+		Attributes:  JoinAttrs(attributes),
+		Children:    children,
+		SelfClosing: selfClosing,
+	}
+}
+
+// NewAttr joins the parts of an attribute value, Raw parts are written as is and the other parts are escaped.
+// A single bool part makes it a boolean attribute which is nil when false.
+func NewAttr(key string, parts ...interface{}) *Attribute {
+	if len(parts) == 1 {
+		if b, ok := parts[0].(bool); ok {
+			if !b {
+				return nil
+			}
+			return NewBoolAttr(key)
+		}
+	}
+	value := ""
+	trusted := true
+	for _, p := range parts {
+		if _, ok := p.(Raw); !ok {
+			trusted = false
+		}
+		value += escapeAttr(p)
+	}
+	if !trusted && lo.Contains(urlAttrs, key) {
+		value = sanitizeUrl(value)
+	}
+	return &Attribute{Key: key, Value: &Literal{Str: &value}}
+}
+
+// NewBoolAttr returns a boolean attribute like disabled which is rendered without a value.
+func NewBoolAttr(key string) *Attribute {
+	return &Attribute{Key: key}
+}
+
+// SpreadAttrs returns the attributes for the keys of a M or MS in sorted order, values are
+// converted like in NewAttr so false and nil values are left out.
+func SpreadAttrs(v interface{}) []*Attribute {
+	m := spreadMap(v)
+	keys := lo.Keys(m)
+	sort.Strings(keys)
+	attrs := []*Attribute{}
+	for _, k := range keys {
+		if m[k] == nil {
+			continue
+		}
+		if a := NewAttr(k, m[k]); a != nil {
+			attrs = append(attrs, a)
+		}
+	}
+	return attrs
+}
+
+// JoinAttrs joins the groups of attributes of an element leaving out nil attributes, an attribute
+// which is set again replaces the previous one except for class where the values are joined.
+func JoinAttrs(groups ...[]*Attribute) []*Attribute {
+	attrs := []*Attribute{}
+	for _, group := range groups {
+		for _, a := range group {
+			if a == nil {
+				continue
+			}
+			_, i, found := lo.FindIndexOf(attrs, func(prev *Attribute) bool {
+				return prev.Key == a.Key
+			})
+			if !found {
+				attrs = append(attrs, a)
+			} else if a.Key == "class" && attrs[i].Value != nil && a.Value != nil && a.Value.Str != nil {
+				value := strings.TrimSpace(*attrs[i].Value.Str + " " + *a.Value.Str)
+				attrs[i] = &Attribute{Key: a.Key, Value: &Literal{Str: &value}}
+			} else {
+				attrs[i] = a
+			}
+		}
+	}
+	return attrs
+}
+
+// SpreadProps sets the keys of the spread maps in the props of a component.
+func SpreadProps(props M, spreads ...interface{}) M {
+	for _, v := range spreads {
+		for k, v := range spreadMap(v) {
+			props[k] = v
+		}
+	}
+	return props
+}
+
+func spreadMap(v interface{}) M {
+	switch it := v.(type) {
+	case nil:
+		return M{}
+	case M:
+		return it
+	case map[string]interface{}:
+		return it
+	case MS:
+		return lo.MapValues(it, func(v string, _ string) interface{} { return v })
+	case map[string]string:
+		return lo.MapValues(it, func(v string, _ string) interface{} { return v })
+	default:
+		panic(eris.Errorf("can't spread %+v of type %T, expected gsx.M or gsx.MS", v, v))
+	}
+}
+
+func ClassNames(names []string, enabled []bool) Raw {
+	classes := []string{}
+	for i, name := range names {
+		if enabled[i] {
+			classes = append(classes, name)
+		}
+	}
+	return Raw(strings.Join(classes, " "))
+}
+
+func RenderComponent(c *Context, name string, props M, children []*Tag) *Tag {
+	comp, ok := compMap[name]
+	if !ok {
+		panic(eris.Errorf("component %s is not registered", name))
+	}
+	compContext := c.Clone(comp.Name)
+	children, slots := splitSlots(children)
+	compContext.Set("children", children)
+	compContext.Set("slots", slots)
+	return NewFragment(comp.render(compContext, props))
+}
+
+// RenderSlot renders the content passed to the component for the named slot, or the children for an
+// unnamed slot, and the fallback when nothing was passed for it.
+func RenderSlot(c *Context, name string, fallback func() []*Tag) *Tag {
+	var content []*Tag
+	if name == "" {
+		content, _ = c.Get("children").([]*Tag)
+	} else if slots, ok := c.Get("slots").(map[string][]*Tag); ok {
+		content = slots[name]
+	}
+	if len(content) == 0 {
+		return NewFragment(fallback())
+	}
+	return NewFragment(content)
+}
+
+// ForEach renders f for every item of the slice, array, map, int, channel or iterator function with the
+// index and item set in a new context, map keys are iterated in sorted order. When there are no items
+// the empty function is rendered instead if it is not nil. With a single loop variable it is set to the
+// index or key like in go, except for channels and single value iterators where it is set to the item.
+func ForEach(c *Context, data interface{}, index, key string, f, empty func(c *Context) []*Tag) *Tag {
+	newTag := NewFragment(nil)
+	each := func(i, item interface{}) bool {
+		compContext := Scope(c)
+		compContext.data[index] = i
+		if key != "" {
+			compContext.data[key] = item
+		}
+		newTag.Children = append(newTag.Children, f(compContext)...)
+		return true
+	}
+	count := 0
+	v := indirect(reflect.ValueOf(data))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		count = v.Len()
+		for i := 0; i < count; i++ {
+			each(i, v.Index(i).Interface())
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessKey(keys[i].Interface(), keys[j].Interface())
+		})
+		count = len(keys)
+		for _, k := range keys {
+			each(k.Interface(), v.MapIndex(k).Interface())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, _ := toInt(v.Interface())
+		for i := 0; i < n; i++ {
+			each(i, i)
+			count++
+		}
+	case reflect.Chan:
+		for {
+			item, ok := v.Recv()
+			if !ok {
+				break
+			}
+			if key == "" {
+				each(item.Interface(), nil)
+			} else {
+				each(count, item.Interface())
+			}
+			count++
+		}
+	case reflect.Func:
+		count = iterate(v, index, key, each)
+	case reflect.Invalid:
+	default:
+		panic(eris.Errorf("can't range over %+v of type %s", data, v.Type()))
+	}
+	if count == 0 && empty != nil {
+		newTag.Children = empty(c)
+	}
+	return newTag
+}
+
+// iterate calls an iterator function like func(yield func(V) bool) or func(yield func(K, V) bool)
+// with a yield function which renders the items.
+func iterate(v reflect.Value, index, key string, each func(i, item interface{}) bool) int {
+	t := v.Type()
+	if t.NumIn() != 1 || t.In(0).Kind() != reflect.Func || t.In(0).NumIn() < 1 || t.In(0).NumIn() > 2 {
+		panic(eris.Errorf("can't range over function of type %s", t))
+	}
+	count := 0
+	yield := reflect.MakeFunc(t.In(0), func(args []reflect.Value) []reflect.Value {
+		if len(args) == 2 {
+			each(args[0].Interface(), args[1].Interface())
+		} else if key == "" {
+			each(args[0].Interface(), nil)
+		} else {
+			each(count, args[0].Interface())
+		}
+		count++
+		if t.In(0).NumOut() == 0 {
+			return nil
+		}
+		return []reflect.Value{reflect.ValueOf(true)}
+	})
+	v.Call([]reflect.Value{yield})
+	return count
+}
+
+func lessKey(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return fa < fb
+		}
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// Scope returns a new context with the data of the context for the body of a loop.
+func Scope(c *Context) *Context {
+	name, _ := c.Get("funcName").(string)
+	return c.Clone(name)
+}
+
+func IsTruthy(v interface{}) bool {
+	return isTruthy(v)
+}
+
+// Ref returns the value of an expression like todo.Text from the data in the context.
+func Ref(c *Context, ref string) interface{} {
+	return getRefValue(c, ref)
+}
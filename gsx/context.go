@@ -2,6 +2,9 @@ package gsx
 
 import (
 	"context"
+	"runtime"
+
+	"github.com/rotisserie/eris"
 )
 
 type HX struct {
@@ -15,12 +18,10 @@ type HX struct {
 
 type Context struct {
 	context.Context
-	hx      *HX
-	data    M
-	meta    M
-	links   map[string]link
-	scripts map[string]bool
-	styles  M
+	hx         *HX
+	hxResponse *hxResponse
+	data       M
+	head       *head
 }
 
 func NewContext(c context.Context, hx *HX) *Context {
@@ -28,10 +29,7 @@ func NewContext(c context.Context, hx *HX) *Context {
 		Context: c,
 		hx:      hx,
 		data:    M{},
-		meta:    M{},
-		links:   map[string]link{},
-		scripts: map[string]bool{},
-		styles:  M{},
+		head:    newHead(),
 	}
 }
 
@@ -47,42 +45,69 @@ func (c *Context) Set(k string, v interface{}) {
 	c.data[k] = v
 }
 
-func (c *Context) Meta(meta M) {
-	c.meta = meta
-}
-
-func (c *Context) AddMeta(k, v string) {
-	c.meta[k] = v
-}
-
-func (c *Context) Link(rel, href, t, as string) {
-	c.links[href] = link{rel, href, t, as}
+func (c *Context) Data(data M) {
+	c.data = data
 }
 
-func (c *Context) Script(src string, sdefer bool) {
-	c.scripts[src] = sdefer
+// Render renders the template with the data in the context, it panics with a *TemplateError
+// if the template can't be parsed or rendered.
+func (c *Context) Render(tpl string) []*Tag {
+	tags, err := c.render(tpl)
+	if err != nil {
+		_, file, line, _ := runtime.Caller(1)
+		err.setCaller(file, line)
+		panic(err)
+	}
+	return tags
 }
 
-func (c *Context) Data(data M) {
-	c.data = data
+// RenderE is like Render but returns the *TemplateError instead of panicking.
+func (c *Context) RenderE(tpl string) ([]*Tag, error) {
+	tags, err := c.render(tpl)
+	if err != nil {
+		_, file, line, _ := runtime.Caller(1)
+		err.setCaller(file, line)
+		return nil, err
+	}
+	return tags, nil
 }
 
-func (c *Context) Styles(s M) {
-	c.styles = s
+func (c *Context) render(tpl string) (tags []*Tag, err *TemplateError) {
+	defer func() {
+		if r := recover(); r != nil {
+			name, _ := c.Get("funcName").(string)
+			err = newTemplateError(name, tpl, r)
+		}
+	}()
+	name, _ := c.Get("funcName").(string)
+	addTemplate(name, tpl)
+	if f, ok := compiledTemplates[tpl]; ok && !DevMode {
+		return f(c), nil
+	}
+	return c.Interpret(tpl), nil
 }
 
-func (c *Context) Render(tpl string) []*Tag {
+// Interpret renders the template without using the compiled render function registered for it.
+func (c *Context) Interpret(tpl string) []*Tag {
 	name, ok := c.Get("funcName").(string)
 	if !ok {
-		panic("funcName is required")
+		panic(eris.New("funcName is required"))
 	}
-	tags := parse(name, tpl)
-	return populate(c, tags)
+	return populate(c, getTemplate(name, tpl))
 }
 
+// Clone returns a context for the component or loop with a copy of the data, it has the same request
+// context and htmx info and the assets and htmx response headers added with it are added to the page.
 func (c *Context) Clone(name string) *Context {
 	newCtx := &Context{
-		data: M{},
+		Context:    c.Context,
+		hx:         c.hx,
+		hxResponse: c.getHXResponse(),
+		data:       M{},
+		head:       c.getHead(),
+	}
+	if newCtx.Context == nil {
+		newCtx.Context = context.Background()
 	}
 	for k, v := range c.data {
 		newCtx.data[k] = v
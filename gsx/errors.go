@@ -0,0 +1,102 @@
+package gsx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/rotisserie/eris"
+)
+
+// TemplateError is returned by RenderE when a template fails to parse or render. File and Line are
+// the position of the Render call in the go file, TemplateLine and Column the position in the template.
+type TemplateError struct {
+	Name         string
+	File         string
+	Line         int
+	TemplateLine int
+	Column       int
+	Snippet      string
+	Err          error
+}
+
+func (e *TemplateError) Error() string {
+	s := e.Name
+	if e.File != "" {
+		s += fmt.Sprintf(" %s:%d", e.File, e.Line)
+	}
+	if e.TemplateLine > 0 {
+		s += fmt.Sprintf(" template %d:%d", e.TemplateLine, e.Column)
+	}
+	return strings.TrimSpace(s) + ": " + e.Err.Error()
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// posError is panicked while rendering a tag so that the error has the position of the tag.
+type posError struct {
+	pos lexer.Position
+	err error
+}
+
+func (e *posError) Error() string {
+	return e.err.Error()
+}
+
+// newTemplateError converts the error or recovered panic into a TemplateError for the template.
+func newTemplateError(name, tpl string, r interface{}) *TemplateError {
+	var err error
+	switch it := r.(type) {
+	case *TemplateError:
+		return it
+	case error:
+		err = it
+	default:
+		err = eris.Errorf("%+v", it)
+	}
+	e := &TemplateError{Name: name, Err: err}
+	var pe *posError
+	var perr participle.Error
+	if errors.As(err, &pe) {
+		e.Err = pe.err
+		e.setPos(tpl, pe.pos)
+	} else if errors.As(err, &perr) {
+		e.Err = errors.New(perr.Message())
+		e.setPos(tpl, perr.Position())
+	}
+	return e
+}
+
+func (e *TemplateError) setPos(tpl string, pos lexer.Position) {
+	e.TemplateLine = pos.Line
+	e.Column = pos.Column
+	lines := strings.Split(tpl, "\n")
+	if pos.Line < 1 || pos.Line > len(lines) {
+		return
+	}
+	line := lines[pos.Line-1]
+	caret := ""
+	for i, r := range []rune(line) {
+		if i >= pos.Column-1 {
+			break
+		}
+		if r == '\t' {
+			caret += "\t"
+		} else {
+			caret += " "
+		}
+	}
+	e.Snippet = fmt.Sprintf("%d | %s\n%s | %s^", pos.Line, line, strings.Repeat(" ", len(fmt.Sprint(pos.Line))), caret)
+}
+
+// setCaller sets the position of the Render call if the error does not have one yet.
+func (e *TemplateError) setCaller(file string, line int) {
+	if e.File == "" {
+		e.File = file
+		e.Line = line
+	}
+}
@@ -0,0 +1,97 @@
+package gsx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BrokenTodo(c *Context, todo *TodoData) []*Tag {
+	return c.Render(`
+		<li>
+			<span>{todo.Txt}</span>
+		</li>
+	`)
+}
+
+func TestRenderE(t *testing.T) {
+	r := require.New(t)
+	c := &Context{data: M{"funcName": "TestRenderE", "name": "gromer"}}
+	_, err := c.RenderE(`
+		<div>
+			<span>{name}</span>
+		</p>
+	`)
+	var tErr *TemplateError
+	r.True(errors.As(err, &tErr))
+	r.Equal("TestRenderE", tErr.Name)
+	r.Contains(tErr.File, "errors_test.go")
+	r.Equal(21, tErr.Line)
+	r.Equal(4, tErr.TemplateLine)
+	r.Equal(3, tErr.Column)
+	r.Equal("closing tag </p> does not match <div> opened at 2:3", tErr.Err.Error())
+	r.Equal("4 | \t\t</p>\n  | \t\t^", tErr.Snippet)
+
+	_, err = c.RenderE(`
+		<div>
+			<span {name}</span>
+		</div>
+	`)
+	r.True(errors.As(err, &tErr))
+	r.Equal(3, tErr.TemplateLine)
+	r.Equal(10, tErr.Column)
+	r.Equal("3 | \t\t\t<span {name}</span>\n  | \t\t\t      ^", tErr.Snippet)
+
+	_, err = c.RenderE(`<span>{Unknown(name)}</span>`)
+	r.EqualError(err, "TestRenderE "+tErr.File+":46 template 1:7: unknown function Unknown")
+}
+
+func TestUnclosedError(t *testing.T) {
+	r := require.New(t)
+	_, err := Parse("todos.go:10", "<div>\n\t<span>\"x\"\n</div>")
+	var tErr *TemplateError
+	r.True(errors.As(err, &tErr))
+	r.Equal(3, tErr.TemplateLine)
+	r.Equal("closing tag </div> does not match <span> opened at 2:2", tErr.Err.Error())
+
+	_, err = Parse("todos.go:10", "<div>\n\t<span>\"x\"")
+	r.True(errors.As(err, &tErr))
+	r.Equal(2, tErr.TemplateLine)
+	r.Equal(2, tErr.Column)
+	r.Equal("unclosed <span> opened at 2:2", tErr.Err.Error())
+
+	_, err = Parse("todos.go:10", "<ul>\n\tfor _, v := range items {\n\t\treturn (\n\t\t\t<li>{v}\n\t\t)\n\t}\n</ul>")
+	r.True(errors.As(err, &tErr))
+	r.Equal("unclosed <li> opened at 4:4", tErr.Err.Error())
+}
+
+func TestRenderComponentError(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(BrokenTodo, nil, "todo")
+	c := &Context{data: M{"funcName": "TestRenderComponentError", "todo": &TodoData{ID: "1"}}}
+	_, err := c.RenderE(`
+		<ul>
+			<BrokenTodo todo={todo} />
+		</ul>
+	`)
+	var tErr *TemplateError
+	r.True(errors.As(err, &tErr))
+	r.Equal("BrokenTodo", tErr.Name)
+	r.Equal(11, tErr.Line)
+	r.Equal(3, tErr.TemplateLine)
+	r.EqualError(tErr.Err, "gsx.TodoData has no field or method Txt")
+	r.Panics(func() {
+		c.Render(`<BrokenTodo todo={todo} />`)
+	})
+}
+
+func TestParseError(t *testing.T) {
+	r := require.New(t)
+	_, err := Parse("todos.go:10", "<div>\n\t<span>{name +}</span>\n</div>")
+	var tErr *TemplateError
+	r.True(errors.As(err, &tErr))
+	r.Equal("todos.go:10", tErr.Name)
+	r.Equal(2, tErr.TemplateLine)
+	r.Equal(14, tErr.Column)
+}
@@ -0,0 +1,567 @@
+package gsx
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/rotisserie/eris"
+)
+
+// Expr is an expression inside {...} in a template, it supports field paths, indexing, method and
+// function calls, comparisons, boolean operators and arithmetic with the usual precedence.
+type Expr struct {
+	Pos   lexer.Position
+	Left  *AndExpr   `@@`
+	Right []*AndExpr `( "|" "|" @@ )*`
+}
+
+type AndExpr struct {
+	Left  *CmpExpr   `@@`
+	Right []*CmpExpr `( "&" "&" @@ )*`
+}
+
+type CmpExpr struct {
+	Left  *AddExpr `@@`
+	Op    string   `( @( "=" "=" | "!" "=" | "<" "=" | ">" "=" | "<" | ">" )`
+	Right *AddExpr `  @@ )?`
+}
+
+type AddExpr struct {
+	Left  *MulExpr  `@@`
+	Right []*OpExpr `@@*`
+}
+
+type OpExpr struct {
+	Op    string   `@( "+" | "-" )`
+	Right *MulExpr `@@`
+}
+
+type MulExpr struct {
+	Left  *UnaryExpr   `@@`
+	Right []*MulOpExpr `@@*`
+}
+
+type MulOpExpr struct {
+	Op    string     `@( "*" | "/" | "%" )`
+	Right *UnaryExpr `@@`
+}
+
+type UnaryExpr struct {
+	Not     *UnaryExpr   `  "!" @@`
+	Neg     *UnaryExpr   `| "-" @@`
+	Postfix *PostfixExpr `| @@`
+}
+
+type PostfixExpr struct {
+	Primary  *Primary  `@@`
+	Suffixes []*Suffix `@@*`
+}
+
+type Suffix struct {
+	Field *string `  "." @Ident`
+	Index *Expr   `| "[" @@ "]"`
+	Call  *Call   `| @@`
+}
+
+type Call struct {
+	Args []*Expr `"(" ( @@ ( "," @@ )* )? ")"`
+}
+
+type Primary struct {
+	Float *float64 `  @Float`
+	Int   *int     `| @Int`
+	Str   *string  `| @String`
+	Ident *string  `| @Ident`
+	Sub   *Expr    `| "(" @@ ")"`
+}
+
+var (
+	exprParser = participle.MustBuild[Expr]()
+	exprCache  = sync.Map{}
+)
+
+// ParseExpr parses the source of an expression like todo.Text or count > 0.
+func ParseExpr(s string) (*Expr, error) {
+	if v, ok := exprCache.Load(s); ok {
+		return v.(*Expr), nil
+	}
+	e, err := exprParser.ParseString("", s)
+	if err != nil {
+		return nil, err
+	}
+	exprCache.Store(s, e)
+	return e, nil
+}
+
+func (e *Expr) String() string {
+	s := e.Left.String()
+	for _, r := range e.Right {
+		s += " || " + r.String()
+	}
+	return s
+}
+
+func (e *AndExpr) String() string {
+	s := e.Left.String()
+	for _, r := range e.Right {
+		s += " && " + r.String()
+	}
+	return s
+}
+
+func (e *CmpExpr) String() string {
+	if e.Right == nil {
+		return e.Left.String()
+	}
+	return e.Left.String() + " " + e.Op + " " + e.Right.String()
+}
+
+func (e *AddExpr) String() string {
+	s := e.Left.String()
+	for _, r := range e.Right {
+		s += " " + r.Op + " " + r.Right.String()
+	}
+	return s
+}
+
+func (e *MulExpr) String() string {
+	s := e.Left.String()
+	for _, r := range e.Right {
+		s += " " + r.Op + " " + r.Right.String()
+	}
+	return s
+}
+
+func (e *UnaryExpr) String() string {
+	if e.Not != nil {
+		return "!" + e.Not.String()
+	} else if e.Neg != nil {
+		return "-" + e.Neg.String()
+	}
+	return e.Postfix.String()
+}
+
+func (e *PostfixExpr) String() string {
+	s := e.Primary.String()
+	for _, suffix := range e.Suffixes {
+		if suffix.Field != nil {
+			s += "." + *suffix.Field
+		} else if suffix.Index != nil {
+			s += "[" + suffix.Index.String() + "]"
+		} else if suffix.Call != nil {
+			args := []string{}
+			for _, a := range suffix.Call.Args {
+				args = append(args, a.String())
+			}
+			s += "(" + strings.Join(args, ", ") + ")"
+		}
+	}
+	return s
+}
+
+func (e *Primary) String() string {
+	if e.Float != nil {
+		return strconv.FormatFloat(*e.Float, 'f', -1, 64)
+	} else if e.Int != nil {
+		return strconv.Itoa(*e.Int)
+	} else if e.Str != nil {
+		return *e.Str
+	} else if e.Ident != nil {
+		return *e.Ident
+	}
+	return "(" + e.Sub.String() + ")"
+}
+
+func evalExpr(c *Context, e *Expr) interface{} {
+	v := evalAnd(c, e.Left)
+	if len(e.Right) == 0 {
+		return v
+	}
+	if isTruthy(v) {
+		return true
+	}
+	for _, r := range e.Right {
+		if isTruthy(evalAnd(c, r)) {
+			return true
+		}
+	}
+	return false
+}
+
+func evalAnd(c *Context, e *AndExpr) interface{} {
+	v := evalCmp(c, e.Left)
+	if len(e.Right) == 0 {
+		return v
+	}
+	if !isTruthy(v) {
+		return false
+	}
+	for _, r := range e.Right {
+		if !isTruthy(evalCmp(c, r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func evalCmp(c *Context, e *CmpExpr) interface{} {
+	left := evalAdd(c, e.Left)
+	if e.Right == nil {
+		return left
+	}
+	right := evalAdd(c, e.Right)
+	switch e.Op {
+	case "==":
+		return isEqual(left, right)
+	case "!=":
+		return !isEqual(left, right)
+	}
+	cmp := compare(left, right)
+	switch e.Op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func evalAdd(c *Context, e *AddExpr) interface{} {
+	v := evalMul(c, e.Left)
+	for _, r := range e.Right {
+		v = arithmetic(r.Op, v, evalMul(c, r.Right))
+	}
+	return v
+}
+
+func evalMul(c *Context, e *MulExpr) interface{} {
+	v := evalUnary(c, e.Left)
+	for _, r := range e.Right {
+		v = arithmetic(r.Op, v, evalUnary(c, r.Right))
+	}
+	return v
+}
+
+func evalUnary(c *Context, e *UnaryExpr) interface{} {
+	if e.Not != nil {
+		return !isTruthy(evalUnary(c, e.Not))
+	} else if e.Neg != nil {
+		return arithmetic("-", 0, evalUnary(c, e.Neg))
+	}
+	return evalPostfix(c, e.Postfix)
+}
+
+func evalPostfix(c *Context, e *PostfixExpr) interface{} {
+	p := e.Primary
+	var v interface{}
+	if p.Float != nil {
+		v = *p.Float
+	} else if p.Int != nil {
+		v = *p.Int
+	} else if p.Str != nil {
+		s, err := strconv.Unquote(*p.Str)
+		if err != nil {
+			panic(eris.Wrapf(err, "invalid string %s", *p.Str))
+		}
+		v = s
+	} else if p.Sub != nil {
+		v = evalExpr(c, p.Sub)
+	} else {
+		v = evalIdent(c, *p.Ident, len(e.Suffixes) > 0 && e.Suffixes[0].Call != nil)
+	}
+	for i, suffix := range e.Suffixes {
+		if suffix.Field != nil {
+			called := i+1 < len(e.Suffixes) && e.Suffixes[i+1].Call != nil
+			v = getField(v, *suffix.Field, called)
+		} else if suffix.Index != nil {
+			v = getIndex(v, evalExpr(c, suffix.Index))
+		} else if suffix.Call != nil {
+			args := []interface{}{}
+			for _, a := range suffix.Call.Args {
+				args = append(args, evalExpr(c, a))
+			}
+			v = callFunc(v, args)
+		}
+	}
+	return v
+}
+
+func evalIdent(c *Context, name string, called bool) interface{} {
+	switch name {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "nil":
+		return nil
+	}
+	if v, ok := c.data[name]; ok {
+		return v
+	}
+	if f, ok := funcMap[name]; ok {
+		if called {
+			return f
+		}
+		return callFunc(f, nil)
+	}
+	if f, ok := builtinFuncs[name]; ok && called {
+		return f
+	}
+	if called {
+		panic(eris.Errorf("unknown function %s", name))
+	}
+	return nil
+}
+
+var builtinFuncs = map[string]interface{}{
+	"len": func(v interface{}) int {
+		rv := indirect(reflect.ValueOf(v))
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.String, reflect.Chan:
+			return rv.Len()
+		}
+		return 0
+	},
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// getField returns the field, map value or method of data, methods which are not called
+// in the expression are called without arguments.
+func getField(data interface{}, name string, called bool) interface{} {
+	if data == nil {
+		return nil
+	}
+	if m := reflect.ValueOf(data).MethodByName(name); m.IsValid() {
+		if called {
+			return m.Interface()
+		}
+		return callFunc(m.Interface(), nil)
+	}
+	v := indirect(reflect.ValueOf(data))
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if f := v.FieldByName(name); f.IsValid() {
+			return f.Interface()
+		}
+		if v.CanAddr() {
+			if m := v.Addr().MethodByName(name); m.IsValid() {
+				if called {
+					return m.Interface()
+				}
+				return callFunc(m.Interface(), nil)
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			if item := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())); item.IsValid() {
+				return item.Interface()
+			}
+			return nil
+		}
+	}
+	panic(eris.Errorf("%s has no field or method %s", v.Type(), name))
+}
+
+func getIndex(data, index interface{}) interface{} {
+	v := indirect(reflect.ValueOf(data))
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		i, ok := toInt(index)
+		if !ok {
+			panic(eris.Errorf("index of %s must be an int but got %+v", v.Type(), index))
+		}
+		if i < 0 || i >= v.Len() {
+			panic(eris.Errorf("index %d out of range for %s of length %d", i, v.Type(), v.Len()))
+		}
+		return v.Index(i).Interface()
+	case reflect.Map:
+		key := reflect.ValueOf(index)
+		if !key.IsValid() || !key.Type().ConvertibleTo(v.Type().Key()) {
+			panic(eris.Errorf("invalid key %+v for %s", index, v.Type()))
+		}
+		if item := v.MapIndex(key.Convert(v.Type().Key())); item.IsValid() {
+			return item.Interface()
+		}
+		return nil
+	}
+	panic(eris.Errorf("can't index %s", v.Type()))
+}
+
+// callFunc calls the function with the arguments and returns its first result, a non nil error
+// returned as the last result panics.
+func callFunc(f interface{}, args []interface{}) interface{} {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func {
+		panic(eris.Errorf("can't call %+v as it is not a function", f))
+	}
+	t := v.Type()
+	if (!t.IsVariadic() && len(args) != t.NumIn()) || (t.IsVariadic() && len(args) < t.NumIn()-1) {
+		panic(eris.Errorf("expected %d arguments for %s but got %d", t.NumIn(), t, len(args)))
+	}
+	in := []reflect.Value{}
+	for i, a := range args {
+		var argType reflect.Type
+		if t.IsVariadic() && i >= t.NumIn()-1 {
+			argType = t.In(t.NumIn() - 1).Elem()
+		} else {
+			argType = t.In(i)
+		}
+		if a == nil {
+			in = append(in, reflect.Zero(argType))
+			continue
+		}
+		av := reflect.ValueOf(a)
+		if !av.Type().AssignableTo(argType) {
+			if !av.Type().ConvertibleTo(argType) {
+				panic(eris.Errorf("can't use %+v as argument %d of type %s for %s", a, i, argType, t))
+			}
+			av = av.Convert(argType)
+		}
+		in = append(in, av)
+	}
+	out := v.Call(in)
+	if len(out) == 0 {
+		return nil
+	}
+	if last := out[len(out)-1]; len(out) > 1 && last.Type().Implements(reflect.TypeOf((*error)(nil)).Elem()) && !last.IsNil() {
+		panic(last.Interface().(error))
+	}
+	return out[0].Interface()
+}
+
+func isTruthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	if v == nil {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() > 0
+	}
+	return !rv.IsZero()
+}
+
+func toInt(v interface{}) (int, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint()), true
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	if i, ok := toInt(v); ok {
+		return float64(i), true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func isEqual(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return fa == fb
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func compare(a, b interface{}) int {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			if fa < fb {
+				return -1
+			} else if fa > fb {
+				return 1
+			}
+			return 0
+		}
+	}
+	sa, aok := a.(string)
+	sb, bok := b.(string)
+	if !aok || !bok {
+		panic(eris.Errorf("can't compare %+v and %+v", a, b))
+	}
+	return strings.Compare(sa, sb)
+}
+
+func arithmetic(op string, a, b interface{}) interface{} {
+	if op == "+" {
+		_, aok := a.(string)
+		_, bok := b.(string)
+		if aok || bok {
+			return fmt.Sprintf("%v%v", a, b)
+		}
+	}
+	ia, aInt := toInt(a)
+	ib, bInt := toInt(b)
+	if aInt && bInt {
+		switch op {
+		case "+":
+			return ia + ib
+		case "-":
+			return ia - ib
+		case "*":
+			return ia * ib
+		case "/":
+			if ib == 0 {
+				panic(eris.New("division by zero"))
+			}
+			return ia / ib
+		case "%":
+			if ib == 0 {
+				panic(eris.New("division by zero"))
+			}
+			return ia % ib
+		}
+	}
+	fa, aok := toFloat(a)
+	fb, bok := toFloat(b)
+	if !aok || !bok {
+		panic(eris.Errorf("can't apply %s to %+v and %+v", op, a, b))
+	}
+	switch op {
+	case "+":
+		return fa + fb
+	case "-":
+		return fa - fb
+	case "*":
+		return fa * fb
+	case "/":
+		return fa / fb
+	}
+	panic(eris.Errorf("can't apply %s to %+v and %+v", op, a, b))
+}
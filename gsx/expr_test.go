@@ -0,0 +1,139 @@
+package gsx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type User struct {
+	Name  string
+	Admin bool
+	Tags  []string
+	Todos []*TodoData
+	Meta  M
+}
+
+func (u *User) Greeting(prefix string) string {
+	return prefix + " " + u.Name
+}
+
+func (u *User) Initial() string {
+	return u.Name[:1]
+}
+
+func Upper(s string) string {
+	return strings.ToUpper(s)
+}
+
+func Join(sep string, items ...string) string {
+	return strings.Join(items, sep)
+}
+
+func TestExpr(t *testing.T) {
+	r := require.New(t)
+	RegisterFunc(Upper)
+	RegisterFunc(Join)
+	RegisterFunc(WebsiteName)
+	c := &Context{
+		data: M{
+			"count": 3,
+			"price": 2.5,
+			"name":  "gromer",
+			"user": &User{
+				Name:  "Bob",
+				Admin: true,
+				Tags:  []string{"a", "b"},
+				Todos: []*TodoData{{ID: "1", Text: "first"}, {ID: "2", Text: "second", Completed: true}},
+				Meta:  M{"k": "v", "nested": M{"deep": 42}},
+			},
+			"m": map[string]int{"one": 1},
+		},
+	}
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{`count`, 3},
+		{`user.Name`, "Bob"},
+		{`user.Meta.nested.deep`, 42},
+		{`user.Meta["k"]`, "v"},
+		{`m["one"]`, 1},
+		{`user.Tags[1]`, "b"},
+		{`user.Todos[1].Text`, "second"},
+		{`user.Todos[count - 2].Completed`, true},
+		{`user.Greeting("Hello")`, "Hello Bob"},
+		{`user.Initial`, "B"},
+		{`Upper(user.Name)`, "BOB"},
+		{`Join(", ", "a", name)`, "a, gromer"},
+		{`WebsiteName`, "My Website"},
+		{`count == 3`, true},
+		{`count != 3`, false},
+		{`count < 4 && count >= 3`, true},
+		{`count > 5 || user.Admin`, true},
+		{`!user.Admin`, false},
+		{`!missing`, true},
+		{`count + 2 * 3`, 9},
+		{`(count + 2) * 3`, 15},
+		{`count % 2`, 1},
+		{`-count + 1`, -2},
+		{`price * 2`, 5.0},
+		{`"Hello " + user.Name + "!"`, "Hello Bob!"},
+		{`"items: " + count`, "items: 3"},
+		{`name == "gromer"`, true},
+		{`missing`, nil},
+		{`missing.Field`, nil},
+	}
+	for _, test := range tests {
+		r.Equal(test.expected, getRefValue(c, test.expr), test.expr)
+	}
+	r.PanicsWithError("gsx.User has no field or method Nme", func() {
+		getRefValue(c, "user.Nme")
+	})
+	r.Panics(func() {
+		getRefValue(c, "user.Tags[5]")
+	})
+}
+
+func TestExprTemplate(t *testing.T) {
+	r := require.New(t)
+	RegisterFunc(Upper)
+	h := Context{
+		data: M{
+			"funcName": "TestExprTemplate",
+			"todos": []*TodoData{
+				{ID: "1", Text: "My first todo", Completed: true},
+				{ID: "2", Text: "My second todo", Completed: false},
+			},
+		},
+	}
+	nodes := h.Render(`
+		<ul data-count={len(todos)} class={"empty": len(todos) == 0, "full": len(todos) > 1}>
+			for i, v := range todos {
+				return (
+					<li id="todo-{i + 1}">
+						if v.Completed && v.ID != "2" {
+							return (
+								<span>{Upper(v.Text)}</span>
+							)
+						}
+					</li>
+				)
+			}
+		</ul>
+	`)
+	actual := RenderString(nodes)
+	expected := trimLeft(`
+<ul data-count="2" class="full">
+  <li id="todo-1">
+    <span>
+      MY FIRST TODO
+    </span>
+  </li>
+  <li id="todo-2">
+  </li>
+</ul>
+`)
+	r.Equal(expected, actual)
+}
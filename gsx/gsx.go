@@ -1,28 +1,40 @@
 package gsx
 
 import (
+	"bytes"
 	"fmt"
+	"html"
 	"io"
 	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/alecthomas/participle/v2/lexer"
 	_ "github.com/alecthomas/repr"
 	"github.com/rotisserie/eris"
 	"github.com/samber/lo"
 )
 
 var (
-	htmlElements = []string{"a", "abbr", "acronym", "address", "applet", "area", "article", "aside", "audio", "b", "base", "basefont", "bb", "bdo", "big", "blockquote", "body", "br /", "button", "canvas", "caption", "center", "cite", "code", "col", "colgroup", "command", "datagrid", "datalist", "dd", "del", "details", "dfn", "dialog", "dir", "div", "dl", "dt", "em", "embed", "eventsource", "fieldset", "figcaption", "figure", "font", "footer", "form", "frame", "frameset", "h1 to <h6>", "head", "header", "hgroup", "hr /", "html", "i", "iframe", "img", "input", "ins", "isindex", "kbd", "keygen", "label", "legend", "li", "link", "map", "mark", "menu", "meta", "meter", "nav", "noframes", "noscript", "object", "ol", "optgroup", "option", "output", "p", "param", "pre", "progress", "q", "rp", "rt", "ruby", "s", "samp", "script", "section", "select", "small", "source", "span", "strike", "strong", "style", "sub", "sup", "table", "tbody", "td", "textarea", "tfoot", "th", "thead", "time", "title", "tr", "track", "tt", "u", "ul", "var", "video", "wbr"}
+	htmlElements = []string{"a", "abbr", "acronym", "address", "applet", "area", "article", "aside", "audio", "b", "base", "basefont", "bb", "bdo", "big", "blockquote", "body", "br", "button", "canvas", "caption", "center", "cite", "code", "col", "colgroup", "command", "datagrid", "datalist", "dd", "del", "details", "dfn", "dialog", "dir", "div", "dl", "dt", "em", "embed", "eventsource", "fieldset", "figcaption", "figure", "font", "footer", "form", "frame", "frameset", "h1", "h2", "h3", "h4", "h5", "h6", "head", "header", "hgroup", "hr", "html", "i", "iframe", "img", "input", "ins", "isindex", "kbd", "keygen", "label", "legend", "li", "link", "main", "map", "mark", "menu", "meta", "meter", "nav", "noframes", "noscript", "object", "ol", "optgroup", "option", "output", "p", "picture", "param", "pre", "progress", "q", "rp", "rt", "ruby", "s", "samp", "script", "section", "select", "small", "source", "span", "strike", "strong", "style", "sub", "summary", "sup", "table", "tbody", "td", "template", "textarea", "tfoot", "th", "thead", "time", "title", "tr", "track", "tt", "u", "ul", "var", "video", "wbr"}
 	voidElements = []string{"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "param", "source", "track", "wbr"}
-	compMap      = map[string]ComponentFunc{}
-	funcMap      = map[string]interface{}{}
-	refRegex     = regexp.MustCompile(`{(.*?)}`)
+	// rawTextElements have text content which is not parsed as gsx, only textarea substitutes {refs} in it.
+	rawTextElements = []string{"script", "style", "textarea"}
+	inlineElements  = []string{"a", "abbr", "b", "bdi", "bdo", "big", "br", "button", "cite", "code", "data", "dfn", "em", "i", "img", "input", "kbd", "label", "mark", "q", "s", "samp", "select", "small", "span", "strong", "sub", "sup", "textarea", "time", "tt", "u", "var", "wbr"}
+	compMap         = map[string]ComponentFunc{}
+	funcMap         = map[string]interface{}{}
+	refRegex        = regexp.MustCompile(`{(.*?)}`)
+	urlAttrs        = []string{"action", "background", "cite", "formaction", "href", "longdesc", "poster", "src", "usemap"}
+	safeSchemes     = []string{"http", "https", "mailto", "tel"}
 )
 
 type (
+	// Raw marks a value as trusted markup which is written to the output as is,
+	// without any html escaping.
+	Raw           string
 	M             map[string]interface{}
 	MS            map[string]string
 	Arr           []interface{}
@@ -30,22 +42,29 @@ type (
 		Name   string
 		Func   interface{}
 		Args   []string
+		Props  reflect.Type
 		Styles M
 	}
-	link struct {
-		Rel  string
-		Href string
-		Type string
-		As   string
-	}
 )
 
+// RegisterComponent registers the component function f. The props of the component are either bound to
+// the arguments named by args or, when no args are given and f is like func(c *Context, p Props) []*Tag,
+// to the fields of the props struct using their json, default and validate tags. It panics if the arbitrary
+// values or opacity modifiers in the styles are invalid.
 func RegisterComponent(f interface{}, styles M, args ...string) {
 	name := getFunctionName(f)
+	if err := validateStyles(styles); err != nil {
+		panic(eris.Wrapf(err, "styles of %s", name))
+	}
+	var props reflect.Type
+	if t := reflect.TypeOf(f); len(args) == 0 && t.NumIn() == 2 && t.In(1).Kind() == reflect.Struct {
+		props = t.In(1)
+	}
 	compMap[name] = ComponentFunc{
 		Name:   name,
 		Func:   f,
 		Args:   args,
+		Props:  props,
 		Styles: styles,
 	}
 }
@@ -61,245 +80,352 @@ func getFunctionName(temp interface{}) string {
 }
 
 func (comp ComponentFunc) Render(c *Context, tag *Tag) []*Tag {
+	props := M{}
+	for _, a := range tag.Attributes {
+		if a.Spread != nil {
+			SpreadProps(props, evalExpr(c, a.Spread))
+		} else if a.Value == nil {
+			props[a.Key] = true
+		} else if a.Value.Ref != nil {
+			props[a.Key] = evalExpr(c, a.Value.Ref)
+		} else if a.Value.Str != nil {
+			props[a.Key] = substituteString(c, removeQuotes(*a.Value.Str), identity, func(v interface{}) string {
+				return Concat(v)
+			})
+		}
+	}
+	return comp.render(c, props)
+}
+
+func (comp ComponentFunc) render(c *Context, props M) []*Tag {
 	args := []reflect.Value{reflect.ValueOf(c)}
+	if comp.Props != nil {
+		v, err := bindProps(comp.Props, props)
+		if err != nil {
+			panic(eris.Wrapf(err, "component %s", comp.Name))
+		}
+		c.Set("props", v.Interface())
+		for i := 0; i < comp.Props.NumField(); i++ {
+			if f := comp.Props.Field(i); f.IsExported() && f.Tag.Get("json") != "-" {
+				c.Set(propName(f), v.Field(i).Interface())
+			}
+		}
+		args = append(args, v)
+	}
 	funcType := reflect.TypeOf(comp.Func)
 	for i, arg := range comp.Args {
 		if v, ok := c.data[arg]; ok {
 			args = append(args, reflect.ValueOf(v))
-		} else {
-			t := funcType.In(i + 1)
-			v, _ := lo.Find(tag.Attributes, func(a *Attribute) bool {
-				return a.Key == arg
-			})
-			var data interface{}
-			if v.Value.Ref != nil {
-				data = getRefValue(c, *v.Value.Ref)
-			} else if v.Value.Str != nil {
-				data = *v.Value.Str
-			}
-			switch t.Kind() {
-			case reflect.Int:
-				var value int
-				if v, ok := data.(int); ok {
-					value = v
-				} else {
-					s, ok := data.(string)
-					if !ok {
-						panic(eris.Errorf("expected component %s: prop %s to be of type string but got %+v ", comp.Name, arg, data))
-					}
-					value, _ = strconv.Atoi(s)
-				}
-				c.Set(arg, value)
-				args = append(args, reflect.ValueOf(value))
-			case reflect.Bool:
-				var value bool
-				if v, ok := data.(bool); ok {
-					value = v
-				} else {
-					s, ok := data.(string)
-					if !ok {
-						panic(eris.Errorf("expected component %s: prop %s to be of type string but got %+v ", comp.Name, arg, data))
-					}
-					value, _ = strconv.ParseBool(s)
-				}
-				c.Set(arg, value)
-				args = append(args, reflect.ValueOf(value))
-			default:
-				c.Set(arg, data)
-				args = append(args, reflect.ValueOf(data))
-			}
+			continue
+		}
+		v, err := convertProp(props[arg], funcType.In(i+1))
+		if err != nil {
+			panic(eris.Wrapf(err, "component %s: prop %s", comp.Name, arg))
 		}
+		c.Set(arg, v.Interface())
+		args = append(args, v)
 	}
 	result := reflect.ValueOf(comp.Func).Call(args)
-	tags := result[0].Interface().([]*Tag)
-	return populate(c, tags)
+	return result[0].Interface().([]*Tag)
+}
+
+// RenderLayouts renders the tags as the children of the layouts, the last layout is the innermost one.
+// Layouts are skipped for htmx requests as those only swap a part of the page. The title and meta set by
+// the page take precedence over the ones set by the layouts.
+func RenderLayouts(c *Context, tags []*Tag, layouts []func(c *Context) []*Tag) []*Tag {
+	if c.hx != nil || len(layouts) == 0 {
+		return tags
+	}
+	funcName := c.Get("funcName")
+	h := c.getHead()
+	title, pageMeta := h.title, append([]meta{}, h.meta...)
+	for i := len(layouts) - 1; i >= 0; i-- {
+		c.Set("funcName", getFunctionName(layouts[i]))
+		c.Set("children", tags)
+		c.Set("slots", map[string][]*Tag{})
+		tags = layouts[i](c)
+	}
+	c.Set("funcName", funcName)
+	for _, m := range pageMeta {
+		c.AddMeta(m.Key, m.Content)
+	}
+	if title != "" {
+		h.title = title
+	}
+	return tags
 }
 
+// Write renders the page to the writer, the head is flushed before the body is rendered and the body
+// is written in chunks as it is rendered.
 func Write(c *Context, w io.Writer, tags []*Tag) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	r := &renderer{w: w, buf: buf, minify: Minify, stripComments: StripComments}
 	if c.hx == nil {
-		w.Write([]byte("<!DOCTYPE html>\n<html lang='en'>\n<head>\n<meta charset='UTF-8'>\n"))
-		w.Write([]byte("    <meta http-equiv='Content-Type' content='text/html;charset=utf-8'><meta content='utf-8' http-equiv='encoding'>\n"))
-		w.Write([]byte("    <meta name='viewport' content='width=device-width, initial-scale=1, maximum-scale=1, user-scalable=0, viewport-fit=cover'>\n"))
-		for k, v := range c.meta {
-			w.Write([]byte(fmt.Sprintf("    <meta name='%s' content='%s'>\n", k, v)))
-		}
-		for k, v := range c.meta {
-			if k == "title" {
-				w.Write([]byte(fmt.Sprintf("    <title>%s</title>\n", v)))
-			}
-		}
-
-		for _, v := range c.links {
-			if v.Type != "" || v.As != "" {
-				w.Write([]byte(fmt.Sprintf("    <link rel='%s' href='%s' type='%s' as='%s'>\n", v.Rel, v.Href, v.Type, v.As)))
-			} else {
-				w.Write([]byte(fmt.Sprintf("    <link rel='%s' href='%s'>\n", v.Rel, v.Href)))
-			}
-		}
-		funcName := c.Get("funcName").(string)
-		styles := computeCss(c.styles, funcName)
-		w.Write([]byte(fmt.Sprintf("    <style>%s</style>\n", styles)))
-
-		for src, sdefer := range c.scripts {
-			if sdefer {
-				w.Write([]byte(fmt.Sprintf("    <script src='%s' defer='true'></script>\n", src)))
-			} else {
-				w.Write([]byte(fmt.Sprintf("    <script src='%s'></script>\n", src)))
-			}
-		}
-		w.Write([]byte("</head>\n  <body _='on htmx:error(errorInfo) put errorInfo.xhr.response into #error'>\n"))
+		h := c.getHead()
+		buf.WriteString("<!DOCTYPE html>\n<html lang='en'>\n<head>\n<meta charset='UTF-8'>\n")
+		buf.WriteString("    <meta http-equiv='Content-Type' content='text/html;charset=utf-8'><meta content='utf-8' http-equiv='encoding'>\n")
+		buf.WriteString("    <meta name='viewport' content='width=device-width, initial-scale=1, maximum-scale=1, user-scalable=0, viewport-fit=cover'>\n")
+		h.write(buf)
+		buf.WriteString("</head>\n  <body _='on htmx:error(errorInfo) put errorInfo.xhr.response into #error'>\n")
+		r.flush()
 	}
-	out := RenderString(tags)
-	w.Write([]byte(out))
+	r.tags(tags, "")
 	if c.hx == nil {
-		w.Write([]byte("  </body>\n</html>"))
+		buf.WriteString("  </body>\n</html>")
 	}
+	w.Write(buf.Bytes())
+}
+
+// WriteFragment renders the tags to the writer without the page like the response of a htmx request.
+func WriteFragment(w io.Writer, tags []*Tag) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	r := &renderer{w: w, buf: buf, minify: Minify, stripComments: StripComments}
+	r.tags(tags, "")
+	w.Write(buf.Bytes())
 }
 
 func GetComponentStyles() string {
-	css := ""
-	for k, v := range compMap {
-		if v.Styles != nil {
-			css += computeCss(v.Styles, k)
+	s := &stylesheet{}
+	names := lo.Keys(compMap)
+	sort.Strings(names)
+	for _, k := range names {
+		if styles := compMap[k].Styles; styles != nil {
+			s.add(styles, k)
 		}
 	}
-	return css
+	return s.String()
 }
 
-func convert(ref string, i interface{}) interface{} {
-	switch iv := i.(type) {
-	case bool:
-		if strings.Contains(ref, "!") {
-			return !iv
-		} else {
-			return iv
-		}
-	case int:
-		return iv
-	case string:
-		return iv
-	case []*Tag:
-		return iv
-	default:
-		return iv
+// getRefValue evaluates the source of an expression like todo.Text against the data in the context.
+func getRefValue(c *Context, ref string) interface{} {
+	e, err := ParseExpr(ref)
+	if err != nil {
+		panic(eris.Wrapf(err, "invalid expression {%s}", ref))
 	}
+	return evalExpr(c, e)
 }
 
-func getRefValue(c *Context, ref string) interface{} {
-	if ref == "true" {
-		return true
-	} else if ref == "false" {
-		return false
-	} else if f, ok := funcMap[ref]; ok {
-		return f.(func() string)()
-	} else {
-		parts := strings.Split(strings.ReplaceAll(ref, "!", ""), ".")
-		if len(parts) == 2 {
-			if v, ok := c.data[parts[0]]; ok {
-				a := reflect.ValueOf(v)
-				if a.Kind() == reflect.Ptr {
-					i := a.Elem().FieldByName(parts[1]).Interface()
-					return convert(ref, i)
-				} else {
-					i := a.FieldByName(parts[1]).Interface()
-					return convert(ref, i)
-				}
-			}
-		}
-		return convert(ref, c.data[ref])
+func getIfBranch(c *Context, s *IfStatement) []*Statement {
+	if isTruthy(evalExpr(c, s.Condition)) {
+		return s.Statements
+	}
+	if s.Else == nil {
+		return nil
+	}
+	if s.Else.If != nil {
+		return getIfBranch(c, s.Else.If)
 	}
+	return s.Else.Statements
 }
 
 func removeBrackets(s string) string {
 	return strings.ReplaceAll(strings.ReplaceAll(s, "{", ""), "}", "")
 }
 
+// removeQuotes unquotes a string literal of the template keeping the escaped quotes in it.
 func removeQuotes(s string) string {
+	if v, err := strconv.Unquote(s); err == nil {
+		return v
+	}
 	return strings.ReplaceAll(s, `"`, "")
 }
 
-func substituteString(c *Context, v string) string {
-	found := refRegex.FindString(v)
-	if found != "" {
-		varValue := fmt.Sprintf("%v", getRefValue(c, removeBrackets(found)))
-		return strings.ReplaceAll(v, found, varValue)
+func identity(s string) string {
+	return s
+}
+
+// escapeQuotes escapes the quotes in the text of an attribute value.
+func escapeQuotes(s string) string {
+	return strings.ReplaceAll(s, `"`, "&#34;")
+}
+
+func escapeText(v interface{}) string {
+	switch iv := v.(type) {
+	case nil:
+		return ""
+	case Raw:
+		return string(iv)
+	default:
+		return html.EscapeString(fmt.Sprintf("%+v", iv))
+	}
+}
+
+func escapeAttr(v interface{}) string {
+	switch iv := v.(type) {
+	case nil:
+		return ""
+	case Raw:
+		return string(iv)
+	default:
+		return html.EscapeString(fmt.Sprintf("%v", iv))
+	}
+}
+
+// sanitizeUrl replaces urls with a scheme that can execute code like javascript: or data: with
+// about:invalid, relative urls and urls with a safe scheme are returned as is.
+func sanitizeUrl(v string) string {
+	u := strings.TrimSpace(v)
+	if i := strings.IndexAny(u, ":/?#"); i > 0 && u[i] == ':' {
+		scheme := strings.ToLower(u[:i])
+		if !lo.Contains(safeSchemes, scheme) {
+			return "about:invalid"
+		}
 	}
 	return v
 }
 
+// substituteString replaces every {ref} in the string with its value converted by value, the text
+// around the refs is converted by text.
+func substituteString(c *Context, v string, text func(string) string, value func(interface{}) string) string {
+	s := ""
+	last := 0
+	for _, loc := range refRegex.FindAllStringSubmatchIndex(v, -1) {
+		s += text(v[last:loc[0]]) + value(getRefValue(c, v[loc[2]:loc[3]]))
+		last = loc[1]
+	}
+	return s + text(v[last:])
+}
+
+// splitSlots separates the <slot:name> tags passed to a component from its other children.
+func splitSlots(tags []*Tag) ([]*Tag, map[string][]*Tag) {
+	children := []*Tag{}
+	slots := map[string][]*Tag{}
+	for _, t := range tags {
+		if strings.HasPrefix(t.Name, "slot:") {
+			name := strings.TrimPrefix(t.Name, "slot:")
+			slots[name] = append(slots[name], t.Children...)
+		} else {
+			children = append(children, t)
+		}
+	}
+	return children, slots
+}
+
+// withPos adds the position to the recovered panic unless a nested tag or template already did.
+func withPos(r interface{}, pos lexer.Position) interface{} {
+	switch it := r.(type) {
+	case *posError, *TemplateError:
+		return r
+	case error:
+		return &posError{pos, it}
+	default:
+		return &posError{pos, eris.Errorf("%+v", it)}
+	}
+}
+
 func populate(c *Context, tags []*Tag) []*Tag {
+	newTags := []*Tag{}
 	for _, t := range tags {
-		populateTag(c, t)
+		newTags = append(newTags, populateTag(c, t))
 	}
-	return tags
+	return newTags
 }
 
-func populateTag(c *Context, tag *Tag) {
+// populateTag renders the parsed tag with the data in the context into a new tag,
+// the parsed tag is never modified so that it can be reused across renders.
+func populateTag(c *Context, tag *Tag) *Tag {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(withPos(r, tag.Pos))
+		}
+	}()
+	if tag.Fragment {
+		return NewFragment(populate(c, tag.Children))
+	}
 	if tag.Name == "" {
 		if tag.Text.Str == nil && tag.Text.Ref != nil {
-			value := getRefValue(c, *tag.Text.Ref)
+			value := evalExpr(c, tag.Text.Ref)
 			children, ok := value.([]*Tag)
 			if ok {
-				tag.Name = "fragment"
-				tag.Children = children
-			} else {
-				sValue := fmt.Sprintf("%+v", value)
-				tag.Text.Str = &sValue
+				return NewFragment(children)
 			}
+			return NewRawText(value)
+		} else if tag.Text.Text != nil {
+			return NewRawText(Raw(substituteString(c, *tag.Text.Text, identity, escapeText)))
 		} else if loop := tag.Text.For; loop != nil {
-			tag.Name = "fragment"
-			data := getRefValue(c, loop.Reference)
-			statement := loop.Statements[0].ReturnStatement
-			switch reflect.TypeOf(data).Kind() {
-			case reflect.Slice:
-				v := reflect.ValueOf(data)
-				for i := 0; i < v.Len(); i++ {
-					compContext := c.Clone(tag.Name)
-					compContext.data[loop.Index] = i
-					compContext.data[loop.Key] = v.Index(i).Interface()
-					newTags := populate(compContext, cloneTags(statement.Tags))
-					tag.Children = append(tag.Children, newTags...)
+			var empty func(c *Context) []*Tag
+			if len(loop.Else) > 0 {
+				empty = func(c *Context) []*Tag {
+					return populate(c, loop.Else[0].ReturnStatement.Tags)
 				}
 			}
+			statement := loop.Statements[0].ReturnStatement
+			return ForEach(c, evalExpr(c, loop.Reference), loop.Index, loop.Key, func(c *Context) []*Tag {
+				return populate(c, statement.Tags)
+			}, empty)
+		} else if cond := tag.Text.If; cond != nil {
+			if statements := getIfBranch(c, cond); len(statements) > 0 {
+				return NewFragment(populate(c, statements[0].ReturnStatement.Tags))
+			}
+			return NewFragment(nil)
 		}
-	} else {
-		if comp, ok := compMap[tag.Name]; ok {
-			tag.Name = "fragment"
-			if tag.SelfClosing {
-				tag.SelfClosing = false
+		return &Tag{Text: tag.Text}
+	}
+	if tag.Name == "!--" || tag.Name == "!DOCTYPE" {
+		return tag
+	}
+	if comp, ok := compMap[tag.Name]; ok {
+		compContext := c.Clone(comp.Name)
+		children, slots := splitSlots(populate(c, tag.Children))
+		compContext.Set("children", children)
+		compContext.Set("slots", slots)
+		return NewFragment(comp.Render(compContext, tag))
+	}
+	if tag.Name == "Slot" {
+		name := ""
+		for _, a := range tag.Attributes {
+			if a.Key != "name" || a.Value == nil {
+				continue
+			} else if a.Value.Str != nil {
+				name = removeQuotes(*a.Value.Str)
+			} else if a.Value.Ref != nil {
+				name = fmt.Sprint(evalExpr(c, a.Value.Ref))
 			}
-			compContext := c.Clone(comp.Name)
-			nodes := comp.Render(compContext, tag)
-			populate(compContext, tag.Children)
-			compContext.Set("children", tag.Children)
-			populate(compContext, nodes)
-			tag.Children = nodes
-		} else {
-			for _, a := range tag.Attributes {
-				if a.Value.Str != nil {
-					if strings.Contains(*a.Value.Str, "{") {
-						subs := substituteString(c, removeQuotes(*a.Value.Str))
-						a.Value = &Literal{Str: &subs}
-					} else {
-						*a.Value.Str = removeQuotes(*a.Value.Str)
-					}
-				} else if a.Value.Ref != nil {
-					value := getRefValue(c, *a.Value.Ref)
-					subs := fmt.Sprintf("%v", value)
-					a.Value = &Literal{Str: &subs}
-				} else if a.Key == "class" && a.Value.KV != nil {
-					classes := []string{}
-					for _, a := range a.Value.KV {
-						varValue := getRefValue(c, a.Value)
-						if varValue.(bool) {
-							classes = append(classes, removeQuotes(a.Key))
-						}
-					}
-					result := strings.Join(classes, " ")
-					a.Value.Str = &result
+		}
+		return RenderSlot(c, name, func() []*Tag {
+			return populate(c, tag.Children)
+		})
+	}
+	newTag := &Tag{
+		Name:        tag.Name,
+		SelfClosing: tag.SelfClosing,
+	}
+	groups := [][]*Attribute{{}}
+	for _, a := range tag.Attributes {
+		var subs string
+		if a.Spread != nil {
+			groups = append(groups, SpreadAttrs(evalExpr(c, a.Spread)), []*Attribute{})
+			continue
+		} else if a.Value == nil {
+			groups[len(groups)-1] = append(groups[len(groups)-1], NewBoolAttr(a.Key))
+			continue
+		} else if a.Value.Str != nil {
+			subs = substituteString(c, removeQuotes(*a.Value.Str), escapeQuotes, escapeAttr)
+			if strings.Contains(*a.Value.Str, "{") && lo.Contains(urlAttrs, a.Key) {
+				subs = sanitizeUrl(subs)
+			}
+		} else if a.Value.Ref != nil {
+			groups[len(groups)-1] = append(groups[len(groups)-1], NewAttr(a.Key, evalExpr(c, a.Value.Ref)))
+			continue
+		} else if a.Key == "class" && a.Value.KV != nil {
+			classes := []string{}
+			for _, a := range a.Value.KV {
+				if isTruthy(evalExpr(c, a.Value)) {
+					classes = append(classes, removeQuotes(a.Key))
 				}
 			}
-			populate(c, tag.Children)
+			subs = strings.Join(classes, " ")
 		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], &Attribute{
+			Key:   a.Key,
+			Value: &Literal{Str: &subs},
+		})
 	}
+	newTag.Attributes = JoinAttrs(groups...)
+	newTag.Children = populate(c, tag.Children)
+	return newTag
 }
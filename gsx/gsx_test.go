@@ -1,6 +1,10 @@
 package gsx
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -34,7 +38,7 @@ func TodoList(c *Context, todos []*TodoData) []*Tag {
 		<ul id="todo-list" class="relative">
 			for i, v := range todos {
 				return (
-					<Todo todo={v}>
+					<Todo todo={v} />
 				)
 			}
 		</ul>
@@ -63,7 +67,8 @@ func TestComponent(t *testing.T) {
 	RegisterFunc(WebsiteName)
 	h := Context{
 		data: M{
-			"todo": &TodoData{ID: "4", Text: "My fourth todo", Completed: true},
+			"funcName": "TestComponent",
+			"todo":     &TodoData{ID: "4", Text: "My fourth todo", Completed: true},
 		},
 	}
 	nodes := h.Render(`
@@ -84,7 +89,12 @@ func TestComponent(t *testing.T) {
       My fourth todo
     </span>
   </div>
-  <nil>
+  <span>
+    My fourth todo
+  </span>
+  <span>
+    true
+  </span>
   <div class="bottom">
     <span>
       true
@@ -94,7 +104,6 @@ func TestComponent(t *testing.T) {
     </span>
   </div>
 </li>
-
 <li id="todo-4" class="completed">
   <div class="upper">
     <span>
@@ -104,7 +113,6 @@ func TestComponent(t *testing.T) {
       My fourth todo
     </span>
   </div>
-  <nil>
   <div class="bottom">
     <span>
       true
@@ -114,7 +122,6 @@ func TestComponent(t *testing.T) {
     </span>
   </div>
 </li>
-
 `)
 	r.Equal(expected, actual)
 }
@@ -125,8 +132,9 @@ func TestMultipleComponent(t *testing.T) {
 	RegisterComponent(TodoCount, nil, "count")
 	h := Context{
 		data: M{
-			"todo":  &TodoData{ID: "4", Text: "My fourth todo", Completed: true},
-			"count": 10,
+			"funcName": "TestMultipleComponent",
+			"todo":     &TodoData{ID: "4", Text: "My fourth todo", Completed: true},
+			"count":    10,
 		},
 	}
 	nodes := h.Render(`
@@ -144,7 +152,6 @@ func TestMultipleComponent(t *testing.T) {
       My fourth todo
     </span>
   </div>
-  <nil>
   <div class="bottom">
     <span>
       true
@@ -154,14 +161,12 @@ func TestMultipleComponent(t *testing.T) {
     </span>
   </div>
 </li>
-
 <span id="todo-count" class="todo-count" hx-swap-oob="true">
   <strong>
     10
   </strong>
   item left
 </span>
-
 `)
 	r.Equal(expected, actual)
 }
@@ -172,6 +177,7 @@ func TestFor(t *testing.T) {
 	RegisterFunc(WebsiteName)
 	h := Context{
 		data: map[string]interface{}{
+			"funcName": "TestFor",
 			"todos": []*TodoData{
 				{ID: "1", Text: "My first todo", Completed: true},
 				{ID: "2", Text: "My second todo", Completed: false},
@@ -243,7 +249,6 @@ func TestFor(t *testing.T) {
       3
     </a>
   </li>
-
 </ul>
 <ol>
   <li id="todo-1" class="completed">
@@ -255,7 +260,14 @@ func TestFor(t *testing.T) {
         My first todo
       </span>
     </div>
-    <nil>
+    <div class="todo-panel">
+      <span>
+        My first todo
+      </span>
+      <span>
+        true
+      </span>
+    </div>
     <div class="bottom">
       <span>
         true
@@ -265,7 +277,6 @@ func TestFor(t *testing.T) {
       </span>
     </div>
   </li>
-
   <li id="todo-2">
     <div class="upper">
       <span>
@@ -275,7 +286,14 @@ func TestFor(t *testing.T) {
         My second todo
       </span>
     </div>
-    <nil>
+    <div class="todo-panel">
+      <span>
+        My second todo
+      </span>
+      <span>
+        false
+      </span>
+    </div>
     <div class="bottom">
       <span>
         false
@@ -285,7 +303,6 @@ func TestFor(t *testing.T) {
       </span>
     </div>
   </li>
-
   <li id="todo-3">
     <div class="upper">
       <span>
@@ -295,7 +312,14 @@ func TestFor(t *testing.T) {
         My third todo
       </span>
     </div>
-    <nil>
+    <div class="todo-panel">
+      <span>
+        My third todo
+      </span>
+      <span>
+        false
+      </span>
+    </div>
     <div class="bottom">
       <span>
         false
@@ -305,8 +329,6 @@ func TestFor(t *testing.T) {
       </span>
     </div>
   </li>
-
-
 </ol>
 `)
 	r.Equal(expected, actual)
@@ -319,6 +341,7 @@ func TestForComponent(t *testing.T) {
 	RegisterFunc(WebsiteName)
 	h := Context{
 		data: map[string]interface{}{
+			"funcName": "TestForComponent",
 			"todos": []*TodoData{
 				{ID: "1", Text: "My first todo", Completed: true},
 				{ID: "2", Text: "My second todo", Completed: false},
@@ -344,7 +367,6 @@ func TestForComponent(t *testing.T) {
           My first todo
         </span>
       </div>
-      <nil>
       <div class="bottom">
         <span>
           true
@@ -354,7 +376,6 @@ func TestForComponent(t *testing.T) {
         </span>
       </div>
     </li>
-
     <li id="todo-2">
       <div class="upper">
         <span>
@@ -364,7 +385,6 @@ func TestForComponent(t *testing.T) {
           My second todo
         </span>
       </div>
-      <nil>
       <div class="bottom">
         <span>
           false
@@ -374,7 +394,6 @@ func TestForComponent(t *testing.T) {
         </span>
       </div>
     </li>
-
     <li id="todo-3">
       <div class="upper">
         <span>
@@ -384,7 +403,6 @@ func TestForComponent(t *testing.T) {
           My third todo
         </span>
       </div>
-      <nil>
       <div class="bottom">
         <span>
           false
@@ -394,11 +412,599 @@ func TestForComponent(t *testing.T) {
         </span>
       </div>
     </li>
+  </ul>
+</div>
+`)
+	r.Equal(expected, actual)
+}
 
+func TestForRange(t *testing.T) {
+	r := require.New(t)
+	ch := make(chan string, 2)
+	ch <- "x"
+	ch <- "y"
+	close(ch)
+	h := Context{
+		data: M{
+			"funcName": "TestForRange",
+			"scores":   map[string]int{"c": 3, "a": 1, "b": 2},
+			"ids":      map[int]string{10: "ten", 2: "two"},
+			"pair":     [2]string{"left", "right"},
+			"user":     &User{Tags: []string{"go", "htmx"}},
+			"empty":    []string{},
+			"ch":       ch,
+			"seq": func(yield func(string) bool) {
+				_ = yield("s1") && yield("s2")
+			},
+			"seq2": func(yield func(string, int) bool) {
+				_ = yield("k1", 1) && yield("k2", 2)
+			},
+		},
+	}
+	render := func(tpl string) string {
+		return strings.Join(strings.Fields(RenderString(h.Render(tpl))), " ")
+	}
+	r.Equal("<i> a=1 </i> <i> b=2 </i> <i> c=3 </i>", render(`
+		for k, v := range scores {
+			return (<i>{k + "=" + v}</i>)
+		}
+	`))
+	r.Equal("<i> 2 </i> <i> 10 </i>", render(`
+		for k := range ids {
+			return (<i>{k}</i>)
+		}
+	`))
+	r.Equal("<i> 0 </i> <i> 1 </i> <i> 2 </i>", render(`
+		for i := range 3 {
+			return (<i>{i}</i>)
+		}
+	`))
+	r.Equal("<i> 1:right </i>", render(`
+		for i, v := range pair {
+			return (
+				if i == 1 {
+					return (<i>{i + ":" + v}</i>)
+				}
+			)
+		}
+	`))
+	r.Equal("<i> go </i> <i> htmx </i>", render(`
+		for i, tag := range user.Tags {
+			return (<i>{tag}</i>)
+		}
+	`))
+	r.Equal("<i> x </i> <i> y </i>", render(`
+		for v := range ch {
+			return (<i>{v}</i>)
+		}
+	`))
+	r.Equal("<i> 0 s1 </i> <i> 1 s2 </i> <i> k1 1 </i> <i> k2 2 </i>", render(`
+		for i, v := range seq {
+			return (<i>{i} {v}</i>)
+		}
+		for k, v := range seq2 {
+			return (<i>{k} {v}</i>)
+		}
+	`))
+	r.Equal("<p> no items </p>", render(`
+		for i, v := range empty {
+			return (<i>{v}</i>)
+		} else {
+			return (<p>"no items"</p>)
+		}
+	`))
+}
 
-  </ul>
+func TestIf(t *testing.T) {
+	r := require.New(t)
+	h := Context{
+		data: M{
+			"funcName": "TestIf",
+			"todos": []*TodoData{
+				{ID: "1", Text: "My first todo", Completed: true},
+				{ID: "2", Text: "My second todo", Completed: false},
+			},
+			"count": 0,
+		},
+	}
+	nodes := h.Render(`
+		<ul>
+			for i, v := range todos {
+				return (
+					<li>
+						if v.Completed {
+							return (
+								<span>"done"</span>
+							)
+						} else if !v.Text {
+							return (
+								<span>"empty"</span>
+							)
+						} else {
+							return (
+								<span>{v.Text}</span>
+							)
+						}
+					</li>
+				)
+			}
+		</ul>
+		if count {
+			return (
+				<span>{count}</span>
+			)
+		}
+		if count < 3 {
+			return (
+				<b>"few"</b>
+			)
+		}
+		<p>
+			if count <= 0 { return (<i>"none"</i>) }
+		</p>
+	`)
+	actual := RenderString(nodes)
+	expected := trimLeft(`
+<ul>
+  <li>
+    <span>
+      done
+    </span>
+  </li>
+  <li>
+    <span>
+      My second todo
+    </span>
+  </li>
+</ul>
+<b>
+  few
+</b>
+<p>
+  <i>
+    none
+  </i>
+</p>
+`)
+	r.Equal(expected, actual)
+}
+
+func TestEscape(t *testing.T) {
+	r := require.New(t)
+	h := Context{
+		data: M{
+			"funcName": "TestEscape",
+			"todo":     &TodoData{ID: `4" onclick="alert(1)`, Text: "<script>alert('xss')</script>"},
+			"link":     "javascript:alert(1)",
+			"page":     "https://example.com/?a=1&b=2",
+			"markup":   Raw("<b>bold</b>"),
+		},
+	}
+	nodes := h.Render(`
+		<div id="todo-{todo.ID}" title={todo.Text}>
+			<span>{todo.Text}</span>
+			<a href={link}>"link"</a>
+			<a href="{link}">"link"</a>
+			<a href={page}>"page"</a>
+			<p>{markup}</p>
+		</div>
+	`)
+	actual := RenderString(nodes)
+	expected := trimLeft(`
+<div id="todo-4&#34; onclick=&#34;alert(1)" title="&lt;script&gt;alert(&#39;xss&#39;)&lt;/script&gt;">
+  <span>
+    &lt;script&gt;alert(&#39;xss&#39;)&lt;/script&gt;
+  </span>
+  <a href="about:invalid">
+    link
+  </a>
+  <a href="about:invalid">
+    link
+  </a>
+  <a href="https://example.com/?a=1&amp;b=2">
+    page
+  </a>
+  <p>
+    <b>bold</b>
+  </p>
+</div>
+`)
+	r.Equal(expected, actual)
+}
+
+type ButtonProps struct {
+	Label string `json:"label"`
+	Attrs M      `gsx:"rest"`
+}
+
+func Button(c *Context, p ButtonProps) []*Tag {
+	return c.Render(`
+		<button class="button" type="button" {...attrs}>{label}</button>
+	`)
+}
+
+func TestAttributes(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(Button, nil)
+	h := Context{
+		data: M{
+			"funcName": "TestAttributes",
+			"todo":     &TodoData{ID: "1", Completed: true},
+			"attrs":    M{"id": "todo-1", "hx-post": "/todos", "hidden": false, "class": "primary"},
+		},
+	}
+	nodes := h.Render(`
+		<div {...attrs} id="todo-{todo.ID}-wrapper">
+			<input type="checkbox" checked={todo.Completed} disabled={false} required />
+			<select>
+				<option selected>"1"</option>
+			</select>
+			<Button label="Delete" hx-delete={"/todos/" + todo.ID} disabled={todo.Completed} />
+		</div>
+	`)
+	actual := RenderString(nodes)
+	expected := trimLeft(`
+<div class="primary" hx-post="/todos" id="todo-1-wrapper">
+  <input type="checkbox" checked required />
+  <select>
+    <option selected>
+      1
+    </option>
+  </select>
+  <button class="button" type="button" disabled hx-delete="/todos/1">
+    Delete
+  </button>
+</div>
+`)
+	r.Equal(expected, actual)
+	_, err := h.RenderE(`<div {...todo}></div>`)
+	r.EqualError(errors.Unwrap(err), "can't spread &{ID:1 Text: Completed:true} of type *gsx.TodoData, expected gsx.M or gsx.MS")
+}
+
+func TestText(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(Button, nil)
+	h := Context{
+		data: M{
+			"funcName": "TestText",
+			"todo":     &TodoData{ID: "1", Text: "<b>milk</b>"},
+			"count":    3,
+		},
+	}
+	nodes := h.Render(`
+		<div id="todo-{todo.ID}-{count}" title="Buy \"{todo.Text}\" now">
+			<p>Hello {todo.Text}, you have {count} items & it's "fine" (really)</p>
+			<p>{count} left</p>
+			<p>{todo.ID} {count}</p>
+			"quoted" {count}
+			for i, v := range count {
+				return (
+					<span>Item {i} of {count}</span>
+				)
+			}
+			if count > 2 {
+				return (
+					many items
+				)
+			}
+			<Button label="Delete {todo.ID} of {count}" />
+		</div>
+	`)
+	actual := RenderString(nodes)
+	expected := trimLeft(`
+<div id="todo-1-3" title="Buy &#34;&lt;b&gt;milk&lt;/b&gt;&#34; now">
+  <p>
+    Hello &lt;b&gt;milk&lt;/b&gt;, you have 3 items & it's "fine" (really)
+  </p>
+  <p>
+    3 left
+  </p>
+  <p>
+    1 3
+  </p>
+  quoted
+  3
+  <span>
+    Item 0 of 3
+  </span>
+  <span>
+    Item 1 of 3
+  </span>
+  <span>
+    Item 2 of 3
+  </span>
+  many items
+  <button class="button" type="button">
+    Delete 1 of 3
+  </button>
+</div>
+`)
+	r.Equal(expected, actual)
+}
+
+func Pair(c *Context, a, b string) []*Tag {
+	return c.Render(`
+		<>
+			<dt>{a}</dt>
+			<dd>{b}</dd>
+		</>
+	`)
+}
+
+func TestFragment(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(Pair, nil, "a", "b")
+	h := Context{
+		data: M{
+			"funcName": "TestFragment",
+		},
+	}
+	tpl := `
+		<dl>
+			<Pair a="one" b="1" />
+			<Pair a="two" b="2" />
+		</dl>
+		<fragment>real</fragment>
+	`
+	expected := trimLeft(`
+<dl>
+  <dt>
+    one
+  </dt>
+  <dd>
+    1
+  </dd>
+  <dt>
+    two
+  </dt>
+  <dd>
+    2
+  </dd>
+</dl>
+<fragment>
+  real
+</fragment>
+`)
+	r.Equal(expected, RenderString(h.Render(tpl)))
+	r.Equal(expected, RenderString(h.Render(tpl)))
+	_, err := h.RenderE(`<dl><{...attrs}></></dl>`)
+	r.EqualError(errors.Unwrap(err), "fragments can't have attributes")
+}
+
+type userKey struct{}
+
+func Avatar(c *Context, size int) []*Tag {
+	c.AddMeta("author", "gromer")
+	c.Script("/js/avatar.js", true)
+	c.Link("preload", "/img/avatar.png", "", "image")
+	c.Styles(M{"container": "rounded-full"})
+	c.Set("user", c.Value(userKey{}))
+	return c.Render(`
+		<img class="Avatar" src="/img/{user}.png" width={size} />
+	`)
+}
+
+func TestClone(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(Avatar, nil, "size")
+	c := NewContext(context.WithValue(context.Background(), userKey{}, "bob"), nil)
+	c.Set("funcName", "TestClone")
+	c.Script("/js/avatar.js", true)
+	tags := c.Render(`
+		<div>
+			for i := range 2 {
+				return (
+					<Avatar size="32" />
+				)
+			}
+		</div>
+	`)
+	r.Equal([]meta{{"author", "gromer"}}, c.head.meta)
+	r.Equal([]Script{{Src: "/js/avatar.js", Defer: true}}, c.head.scripts)
+	r.Equal([]link{{"preload", "/img/avatar.png", "", "image"}}, c.head.links)
+	r.Equal(map[string]M{"Avatar": {"container": "rounded-full"}}, c.head.styles)
+	w := &bytes.Buffer{}
+	Write(c, w, tags)
+	r.Contains(w.String(), `<script src="/js/avatar.js" defer></script>`)
+	r.Contains(w.String(), ".Avatar {\n  border-radius: 9999px;\n}")
+	r.Contains(w.String(), `<img class="Avatar" src="/img/bob.png" width="32" />`)
+
+	clone := c.Clone("Avatar")
+	r.Equal(c.Context, clone.Context)
+	r.Equal(c.hx, clone.HX(""))
+
+	empty := &Context{data: M{}}
+	clone = empty.Clone("Avatar")
+	r.Nil(empty.Context)
+	r.Equal(context.Background(), clone.Context)
+}
+
+func TestTemplateCache(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(Todo, nil, "todo")
+	tpl := `
+		<ul>
+			for i, v := range todos {
+				return (
+					<Todo todo={v} />
+				)
+			}
+		</ul>
+	`
+	h := Context{
+		data: M{
+			"funcName": "TestTemplateCache",
+			"todos":    []*TodoData{{ID: "1", Text: "My first todo"}},
+		},
+	}
+	first := RenderString(h.Render(tpl))
+	parsed := getTemplate("TestTemplateCache", tpl)
+	before := RenderString(parsed)
+	h.Set("todos", []*TodoData{{ID: "2", Text: "My second todo", Completed: true}})
+	second := RenderString(h.Render(tpl))
+	r.NotEqual(first, second)
+	r.Contains(first, "My first todo")
+	r.Contains(second, "My second todo")
+	r.Same(parsed[0], getTemplate("TestTemplateCache", tpl)[0])
+	r.Equal(before, RenderString(getTemplate("TestTemplateCache", tpl)))
+}
 
+func Card(c *Context, title string) []*Tag {
+	return c.Render(`
+		<div class="card">
+			<header>
+				<Slot name="header">
+					<h1>{title}</h1>
+				</Slot>
+			</header>
+			<Slot />
+			<footer>
+				<Slot name="footer">"no footer"</Slot>
+			</footer>
+		</div>
+	`)
+}
+
+func TestSlots(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(Card, nil, "title")
+	h := Context{
+		data: M{
+			"funcName": "TestSlots",
+			"title":    "parent title",
+			"user":     "bob",
+		},
+	}
+	actual := RenderString(h.Render(`
+		<Card title="card title">
+			<slot:header>
+				<h2>{title}</h2>
+			</slot:header>
+			<p>{user}</p>
+		</Card>
+	`))
+	expected := trimLeft(`
+<div class="card">
+  <header>
+    <h2>
+      parent title
+    </h2>
+  </header>
+  <p>
+    bob
+  </p>
+  <footer>
+    no footer
+  </footer>
+</div>
+`)
+	r.Equal(expected, actual)
+
+	h = Context{
+		data: M{
+			"funcName": "TestSlots",
+		},
+	}
+	actual = RenderString(h.Render(`
+		<Card title="card title">
+			<slot:footer>"custom footer"</slot:footer>
+		</Card>
+	`))
+	expected = trimLeft(`
+<div class="card">
+  <header>
+    <h1>
+      card title
+    </h1>
+  </header>
+  <footer>
+    custom footer
+  </footer>
 </div>
 `)
 	r.Equal(expected, actual)
 }
+
+func RootLayout(c *Context) []*Tag {
+	c.AddMeta("title", "Root")
+	c.AddMeta("author", "gromer")
+	return c.Render(`
+		<main>
+			<Slot />
+		</main>
+	`)
+}
+
+func AdminLayout(c *Context) []*Tag {
+	return c.Render(`
+		<section class="admin">
+			{children}
+		</section>
+	`)
+}
+
+func TestRenderLayouts(t *testing.T) {
+	r := require.New(t)
+	c := NewContext(context.Background(), nil)
+	c.Set("funcName", "TestRenderLayouts")
+	c.AddMeta("title", "Users")
+	tags := RenderLayouts(c, c.Render(`<h1>"Users"</h1>`), []func(c *Context) []*Tag{RootLayout, AdminLayout})
+	expected := trimLeft(`
+<main>
+  <section class="admin">
+    <h1>
+      Users
+    </h1>
+  </section>
+</main>
+`)
+	r.Equal(expected, RenderString(tags))
+	r.Equal("TestRenderLayouts", c.Get("funcName"))
+	r.Equal("Users", c.head.title)
+	r.Equal([]meta{{"author", "gromer"}}, c.head.meta)
+
+	hx := NewContext(context.Background(), &HX{Target: "users"})
+	hx.Set("funcName", "TestRenderLayouts")
+	tags = RenderLayouts(hx, hx.Render(`<h1>"Users"</h1>`), []func(c *Context) []*Tag{RootLayout, AdminLayout})
+	r.Equal("<h1>\n  Users\n</h1>\n", RenderString(tags))
+}
+
+const benchTemplate = `
+	<ul id="todo-list" class="relative">
+		for i, v := range todos {
+			return (
+				<li id="todo-{v.ID}" class={"completed": v.Completed }>
+					<span>{v.Text}</span>
+					<a href="/todos/{v.ID}">"link"</a>
+				</li>
+			)
+		}
+	</ul>
+`
+
+func benchContext() *Context {
+	todos := []*TodoData{}
+	for i := 0; i < 20; i++ {
+		todos = append(todos, &TodoData{ID: strconv.Itoa(i), Text: "My todo", Completed: i%2 == 0})
+	}
+	return &Context{
+		data: M{
+			"funcName": "Benchmark",
+			"todos":    todos,
+		},
+	}
+}
+
+func BenchmarkRenderParseEachTime(b *testing.B) {
+	h := benchContext()
+	for i := 0; i < b.N; i++ {
+		RenderString(populate(h, parse("Benchmark", benchTemplate)))
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	h := benchContext()
+	for i := 0; i < b.N; i++ {
+		RenderString(h.Render(benchTemplate))
+	}
+}
@@ -0,0 +1,262 @@
+package gsx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/samber/lo"
+)
+
+// head has the assets of the page, it is shared by the contexts of the components in the page so that
+// the assets added by them are rendered in the head of the page. The entries are rendered in the order
+// they are added and adding an entry with the same key again replaces it in place.
+type head struct {
+	title   string
+	meta    []meta
+	links   []link
+	scripts []Script
+	jsonLD  []string
+	styles  map[string]M
+}
+
+type meta struct {
+	Key     string
+	Content string
+}
+
+type link struct {
+	Rel  string
+	Href string
+	Type string
+	As   string
+}
+
+// Script is a script in the head of the page.
+type Script struct {
+	Src         string
+	Defer       bool
+	Async       bool
+	Module      bool
+	Integrity   string
+	CrossOrigin string
+}
+
+// OpenGraph is the og: meta of a page which is shown when it is shared.
+type OpenGraph struct {
+	Title       string
+	Description string
+	Type        string
+	Url         string
+	Image       string
+	SiteName    string
+}
+
+// TwitterCard is the twitter: meta of a page which is shown when it is shared on twitter.
+type TwitterCard struct {
+	Card        string
+	Site        string
+	Creator     string
+	Title       string
+	Description string
+	Image       string
+}
+
+func newHead() *head {
+	return &head{
+		styles: map[string]M{},
+	}
+}
+
+func (c *Context) getHead() *head {
+	if c.head == nil {
+		c.head = newHead()
+	}
+	return c.head
+}
+
+// Title sets the title of the page.
+func (c *Context) Title(title string) {
+	c.getHead().title = title
+}
+
+// Meta replaces the meta of the page, the title key sets the title.
+func (c *Context) Meta(meta M) {
+	h := c.getHead()
+	h.meta = nil
+	keys := lo.Keys(meta)
+	sort.Strings(keys)
+	for _, k := range keys {
+		c.AddMeta(k, fmt.Sprint(meta[k]))
+	}
+}
+
+// AddMeta adds a meta tag to the page, keys like og:title are written as property and the others as name.
+// The title key sets the title.
+func (c *Context) AddMeta(k, v string) {
+	h := c.getHead()
+	if k == "title" {
+		h.title = v
+		return
+	}
+	if _, i, ok := lo.FindIndexOf(h.meta, func(m meta) bool { return m.Key == k }); ok {
+		h.meta[i].Content = v
+		return
+	}
+	h.meta = append(h.meta, meta{k, v})
+}
+
+// Link adds a link to the head of the page, links are unique by rel and href.
+func (c *Context) Link(rel, href, t, as string) {
+	h := c.getHead()
+	l := link{rel, href, t, as}
+	if _, i, ok := lo.FindIndexOf(h.links, func(v link) bool { return v.Rel == rel && v.Href == href }); ok {
+		h.links[i] = l
+		return
+	}
+	h.links = append(h.links, l)
+}
+
+// Canonical sets the canonical url of the page.
+func (c *Context) Canonical(href string) {
+	h := c.getHead()
+	h.links = lo.Reject(h.links, func(v link, _ int) bool { return v.Rel == "canonical" })
+	c.Link("canonical", href, "", "")
+}
+
+// Preload adds a preload hint for an asset like a font or image which is needed early, as is the
+// type of asset like font, image, script or style.
+func (c *Context) Preload(href, as, t string) {
+	c.Link("preload", href, t, as)
+}
+
+// Script adds a script to the head of the page, scripts are unique by src.
+func (c *Context) Script(src string, sdefer bool) {
+	c.AddScript(Script{Src: src, Defer: sdefer})
+}
+
+// AddScript adds a script with its attributes to the head of the page, scripts are unique by src.
+func (c *Context) AddScript(s Script) {
+	h := c.getHead()
+	if _, i, ok := lo.FindIndexOf(h.scripts, func(v Script) bool { return v.Src == s.Src }); ok {
+		h.scripts[i] = s
+		return
+	}
+	h.scripts = append(h.scripts, s)
+}
+
+// OpenGraph adds the og: meta of the page, empty fields are left out.
+func (c *Context) OpenGraph(og OpenGraph) {
+	c.addMeta("og:", [][2]string{
+		{"title", og.Title},
+		{"description", og.Description},
+		{"type", og.Type},
+		{"url", og.Url},
+		{"image", og.Image},
+		{"site_name", og.SiteName},
+	})
+}
+
+// Twitter adds the twitter: meta of the page, empty fields are left out.
+func (c *Context) Twitter(card TwitterCard) {
+	c.addMeta("twitter:", [][2]string{
+		{"card", card.Card},
+		{"site", card.Site},
+		{"creator", card.Creator},
+		{"title", card.Title},
+		{"description", card.Description},
+		{"image", card.Image},
+	})
+}
+
+func (c *Context) addMeta(prefix string, values [][2]string) {
+	for _, v := range values {
+		if v[1] != "" {
+			c.AddMeta(prefix+v[0], v[1])
+		}
+	}
+}
+
+// JSONLD adds structured data like a schema.org Article to the page as a json-ld script.
+func (c *Context) JSONLD(data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	h := c.getHead()
+	if !lo.Contains(h.jsonLD, string(b)) {
+		h.jsonLD = append(h.jsonLD, string(b))
+	}
+}
+
+// Styles adds the styles of the page or component rendering with the context to the head of the page,
+// the classes are scoped by the name of the page or component. It panics if the arbitrary values or
+// opacity modifiers in the styles are invalid.
+func (c *Context) Styles(s M) {
+	name, _ := c.Get("funcName").(string)
+	if err := validateStyles(s); err != nil {
+		panic(eris.Wrapf(err, "styles of %s", name))
+	}
+	pageStyles.Store(name, s)
+	c.getHead().styles[name] = s
+}
+
+// write writes the title, meta, links, styles, scripts and json-ld of the page in that order.
+func (h *head) write(buf *bytes.Buffer) {
+	if h.title != "" {
+		fmt.Fprintf(buf, "    <title>%s</title>\n", html.EscapeString(h.title))
+	}
+	for _, m := range h.meta {
+		attr := "name"
+		if strings.Contains(m.Key, ":") && !strings.HasPrefix(m.Key, "twitter:") {
+			attr = "property"
+		}
+		fmt.Fprintf(buf, "    <meta %s=\"%s\" content=\"%s\">\n", attr, html.EscapeString(m.Key), html.EscapeString(m.Content))
+	}
+	for _, l := range h.links {
+		fmt.Fprintf(buf, "    <link rel=\"%s\" href=\"%s\"", html.EscapeString(l.Rel), html.EscapeString(l.Href))
+		if l.Type != "" {
+			fmt.Fprintf(buf, " type=\"%s\"", html.EscapeString(l.Type))
+		}
+		if l.As != "" {
+			fmt.Fprintf(buf, " as=\"%s\"", html.EscapeString(l.As))
+		}
+		if l.Rel == "preload" && l.As == "font" {
+			buf.WriteString(" crossorigin")
+		}
+		buf.WriteString(">\n")
+	}
+	names := lo.Keys(h.styles)
+	sort.Strings(names)
+	styles := &stylesheet{}
+	for _, name := range names {
+		styles.add(h.styles[name], name)
+	}
+	fmt.Fprintf(buf, "    <style>%s</style>\n", styles.String())
+	for _, s := range h.scripts {
+		fmt.Fprintf(buf, "    <script src=\"%s\"", html.EscapeString(s.Src))
+		if s.Module {
+			buf.WriteString(" type=\"module\"")
+		}
+		if s.Async {
+			buf.WriteString(" async")
+		}
+		if s.Defer {
+			buf.WriteString(" defer")
+		}
+		if s.Integrity != "" {
+			fmt.Fprintf(buf, " integrity=\"%s\"", html.EscapeString(s.Integrity))
+		}
+		if s.CrossOrigin != "" {
+			fmt.Fprintf(buf, " crossorigin=\"%s\"", html.EscapeString(s.CrossOrigin))
+		}
+		buf.WriteString("></script>\n")
+	}
+	for _, data := range h.jsonLD {
+		fmt.Fprintf(buf, "    <script type=\"application/ld+json\">%s</script>\n", data)
+	}
+}
@@ -0,0 +1,225 @@
+package gsx
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/samber/lo"
+)
+
+var idRegex = regexp.MustCompile(`^[A-Za-z][\w-]*$`)
+
+// hxResponse has the htmx response headers set by the page and its components, it is shared by their
+// contexts like the head and is written to the response before the status.
+type hxResponse struct {
+	headers     map[string]string
+	trigger     []event
+	afterSettle []event
+}
+
+type event struct {
+	Name   string
+	Detail interface{}
+}
+
+// HXLocation is the HX-Location of a response, only the path is required and the others are the same
+// as the options of htmx.ajax.
+type HXLocation struct {
+	Path    string `json:"path"`
+	Source  string `json:"source,omitempty"`
+	Event   string `json:"event,omitempty"`
+	Handler string `json:"handler,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Swap    string `json:"swap,omitempty"`
+	Values  M      `json:"values,omitempty"`
+	Headers MS     `json:"headers,omitempty"`
+}
+
+func (c *Context) getHXResponse() *hxResponse {
+	if c.hxResponse == nil {
+		c.hxResponse = &hxResponse{headers: map[string]string{}}
+	}
+	return c.hxResponse
+}
+
+func (c *Context) setHXHeader(k, v string) {
+	c.getHXResponse().headers[k] = v
+}
+
+// HXRedirect makes htmx do a full page redirect to the url.
+func (c *Context) HXRedirect(url string) {
+	c.setHXHeader("HX-Redirect", url)
+}
+
+// HXLocation makes htmx load the location without a full page reload like a boosted link.
+func (c *Context) HXLocation(l HXLocation) {
+	if l.Source == "" && l.Event == "" && l.Handler == "" && l.Target == "" && l.Swap == "" && l.Values == nil && l.Headers == nil {
+		c.setHXHeader("HX-Location", l.Path)
+		return
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		panic(eris.Wrap(err, "HX-Location marshal failed"))
+	}
+	c.setHXHeader("HX-Location", string(data))
+}
+
+// HXPushUrl pushes the url into the history of the browser, false prevents htmx from pushing it.
+func (c *Context) HXPushUrl(url string) {
+	c.setHXHeader("HX-Push-Url", url)
+}
+
+// HXReplaceUrl replaces the current url in the location bar, false prevents htmx from replacing it.
+func (c *Context) HXReplaceUrl(url string) {
+	c.setHXHeader("HX-Replace-Url", url)
+}
+
+// HXRefresh makes htmx do a full refresh of the page.
+func (c *Context) HXRefresh() {
+	c.setHXHeader("HX-Refresh", "true")
+}
+
+// HXRetarget swaps the response into the element of the css selector instead of the hx-target.
+func (c *Context) HXRetarget(selector string) {
+	c.setHXHeader("HX-Retarget", selector)
+}
+
+// HXReswap swaps the response with the swap like "outerHTML" instead of the hx-swap.
+func (c *Context) HXReswap(swap string) {
+	c.setHXHeader("HX-Reswap", swap)
+}
+
+// HXTrigger triggers the event on the client when the response is received, the detail is sent as json
+// and is available in event.detail, it can be nil.
+func (c *Context) HXTrigger(name string, detail interface{}) {
+	r := c.getHXResponse()
+	r.trigger = addEvent(r.trigger, name, detail)
+}
+
+// HXTriggerAfterSettle is like HXTrigger but the event is triggered after the response is settled.
+func (c *Context) HXTriggerAfterSettle(name string, detail interface{}) {
+	r := c.getHXResponse()
+	r.afterSettle = addEvent(r.afterSettle, name, detail)
+}
+
+func addEvent(events []event, name string, detail interface{}) []event {
+	for i, e := range events {
+		if e.Name == name {
+			events[i].Detail = detail
+			return events
+		}
+	}
+	return append(events, event{name, detail})
+}
+
+// WriteHX writes the htmx response headers to the header, it has to be called before the status is written.
+func (c *Context) WriteHX(h http.Header) error {
+	if c.hxResponse == nil {
+		return nil
+	}
+	for k, v := range c.hxResponse.headers {
+		h.Set(k, v)
+	}
+	if err := writeEvents(h, "HX-Trigger", c.hxResponse.trigger); err != nil {
+		return err
+	}
+	return writeEvents(h, "HX-Trigger-After-Settle", c.hxResponse.afterSettle)
+}
+
+// writeEvents writes the names of the events when none of them have a detail otherwise it writes
+// the events as a json object.
+func writeEvents(h http.Header, k string, events []event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	names := []string{}
+	details := M{}
+	hasDetail := false
+	for _, e := range events {
+		names = append(names, e.Name)
+		details[e.Name] = e.Detail
+		if e.Detail != nil {
+			hasDetail = true
+		}
+	}
+	if !hasDetail {
+		h.Set(k, strings.Join(names, ", "))
+		return nil
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return eris.Wrapf(err, "%s marshal failed", k)
+	}
+	h.Set(k, string(data))
+	return nil
+}
+
+// OOB marks the elements to be swapped out of band by htmx into the elements with the same id when they are
+// returned with the primary fragment. The swap is like "innerHTML" or "beforeend" and defaults to outerHTML,
+// the target is the id the element is swapped into and is set as the id of the element when it has none.
+// Without a target every element must have an id.
+func (c *Context) OOB(target, swap string, tags []*Tag) []*Tag {
+	if swap == "" {
+		swap = "true"
+	}
+	elements := []*Tag{}
+	result := oobElements(tags, &elements)
+	if target != "" {
+		target = strings.TrimPrefix(target, "#")
+		if !idRegex.MatchString(target) {
+			panic(eris.Errorf("oob target '%s' is not a valid id", target))
+		}
+		if len(elements) != 1 {
+			panic(eris.Errorf("oob target #%s needs a single element but got %d", target, len(elements)))
+		}
+	}
+	for _, t := range elements {
+		id := ""
+		if a, ok := lo.Find(t.Attributes, func(a *Attribute) bool { return a.Key == "id" }); ok && a.Value != nil && a.Value.Str != nil {
+			id = *a.Value.Str
+		}
+		value := swap
+		if target != "" {
+			if id == "" {
+				id = target
+				t.Attributes = JoinAttrs(t.Attributes, []*Attribute{NewAttr("id", Raw(id))})
+			} else if id != target {
+				if swap == "true" {
+					value = "outerHTML"
+				}
+				value += ":#" + target
+			}
+		}
+		if id == "" {
+			panic(eris.Errorf("oob element <%s> must have an id", t.Name))
+		}
+		if !idRegex.MatchString(id) {
+			panic(eris.Errorf("oob element <%s> id '%s' is not a valid id", t.Name, id))
+		}
+		t.Attributes = JoinAttrs(t.Attributes, []*Attribute{NewAttr("hx-swap-oob", Raw(value))})
+	}
+	return result
+}
+
+// oobElements copies the tags and collects the elements in them, the elements of fragments like
+// components are collected instead of the fragment.
+func oobElements(tags []*Tag, elements *[]*Tag) []*Tag {
+	result := []*Tag{}
+	for _, t := range tags {
+		if t.Fragment {
+			result = append(result, NewFragment(oobElements(t.Children, elements)))
+			continue
+		}
+		if t.Name == "" || t.Name == "!--" || t.Name == "!DOCTYPE" {
+			panic(eris.New("oob tags must be elements"))
+		}
+		newTag := *t
+		newTag.Attributes = append([]*Attribute{}, t.Attributes...)
+		*elements = append(*elements, &newTag)
+		result = append(result, &newTag)
+	}
+	return result
+}
@@ -0,0 +1,92 @@
+package gsx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHXResponse(t *testing.T) {
+	r := require.New(t)
+	c := NewContext(context.Background(), &HX{Target: "list"})
+	h := http.Header{}
+	r.NoError(c.WriteHX(h))
+	r.Len(h, 0)
+
+	c.HXPushUrl("/todos?page=2")
+	c.HXRetarget("#todo-list")
+	c.HXReswap("afterbegin")
+	c.HXLocation(HXLocation{Path: "/todos"})
+	c.HXTrigger("todoAdded", nil)
+	c.HXTrigger("closeModal", nil)
+	item := c.Clone("TodoItem")
+	item.HXTriggerAfterSettle("todoAdded", M{"id": 1, "text": "one"})
+	item.HXTrigger("todoAdded", nil)
+	r.NoError(c.WriteHX(h))
+	r.Equal("/todos?page=2", h.Get("HX-Push-Url"))
+	r.Equal("#todo-list", h.Get("HX-Retarget"))
+	r.Equal("afterbegin", h.Get("HX-Reswap"))
+	r.Equal("/todos", h.Get("HX-Location"))
+	r.Equal("todoAdded, closeModal", h.Get("HX-Trigger"))
+	r.Equal(`{"todoAdded":{"id":1,"text":"one"}}`, h.Get("HX-Trigger-After-Settle"))
+
+	c.HXLocation(HXLocation{Path: "/todos", Target: "#main", Values: M{"page": 2}})
+	c.HXTrigger("showMessage", "Todo added")
+	c.HXRedirect("/login")
+	c.HXReplaceUrl("false")
+	c.HXRefresh()
+	h = http.Header{}
+	r.NoError(c.WriteHX(h))
+	r.Equal(`{"path":"/todos","target":"#main","values":{"page":2}}`, h.Get("HX-Location"))
+	r.Equal(`{"closeModal":null,"showMessage":"Todo added","todoAdded":null}`, h.Get("HX-Trigger"))
+	r.Equal("/login", h.Get("HX-Redirect"))
+	r.Equal("false", h.Get("HX-Replace-Url"))
+	r.Equal("true", h.Get("HX-Refresh"))
+
+	c.HXTrigger("bad", make(chan int))
+	r.Error(c.WriteHX(http.Header{}))
+}
+
+func TestOOB(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(TodoCount, nil, "count")
+	c := NewContext(context.Background(), &HX{Target: "todo-list"})
+	c.Set("funcName", "TestOOB")
+	c.Set("count", 3)
+	tags := c.Render(`<li id="todo-1">"one"</li>`)
+	tags = append(tags, c.OOB("", "", c.Render(`<TodoCount /><button id="check-all">"All"</button>`))...)
+	tags = append(tags, c.OOB("#notice", "innerHTML", c.Render(`<p class="notice">"Added"</p>`))...)
+	tags = append(tags, c.OOB("todo-list", "beforeend", c.Render(`<li id="todo-2">"two"</li>`))...)
+	r.Equal(trimLeft(`
+<li id="todo-1">
+  one
+</li>
+<span id="todo-count" class="todo-count" hx-swap-oob="true">
+  <strong>
+    3
+  </strong>
+  item left
+</span>
+<button id="check-all" hx-swap-oob="true">
+  All
+</button>
+<p class="notice" id="notice" hx-swap-oob="innerHTML">
+  Added
+</p>
+<li id="todo-2" hx-swap-oob="beforeend:#todo-list">
+  two
+</li>
+`), RenderString(tags))
+
+	r.PanicsWithError("oob element <p> must have an id", func() {
+		c.OOB("", "", c.Render(`<p>"Added"</p>`))
+	})
+	r.PanicsWithError("oob target '1st' is not a valid id", func() {
+		c.OOB("1st", "", c.Render(`<p>"Added"</p>`))
+	})
+	r.PanicsWithError("oob target #notice needs a single element but got 2", func() {
+		c.OOB("notice", "", c.Render(`<p>"Added"</p><p>"Again"</p>`))
+	})
+}
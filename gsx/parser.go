@@ -1,12 +1,17 @@
 package gsx
 
 import (
+	"bytes"
+	"html"
+	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/goneric/stack"
 	"github.com/rotisserie/eris"
+	"github.com/samber/lo"
 )
 
 type Module struct {
@@ -16,29 +21,45 @@ type Module struct {
 
 type AstNode struct {
 	Pos     lexer.Position
-	Open    *Open    `@@`
+	Comment *string  `"<" "!" "-" "-" @RawString "-" "-" ">"`
+	Doctype []string `| "<" "!" @Ident @Ident* ">"`
 	Close   *Close   `| @@`
+	Open    *Open    `| @@`
+	Raw     *string  `| @RawString`
 	Content *Literal `| @@`
 }
 
 type Open struct {
 	Pos        lexer.Position
-	Name       string       `"<" @Ident`
+	Name       string       `"<" ( @"slot" @":" @Ident | @Ident )?`
 	Attributes []*Attribute `[ @@ { @@ } ]`
 	SelfClose  string       `@("/")? ">"`
 }
 
 type Close struct {
 	Pos  lexer.Position
-	Name string `"<""/"@Ident">"`
+	Name string `"<""/" ( @"slot" @":" @Ident | @Ident )? ">"`
 }
 
 type ForStatement struct {
 	Pos        lexer.Position `"for"`
-	Index      string         `@Ident ","`
-	Key        string         `@Ident`
-	Reference  string         `":""=""range" @Ident`
+	Index      string         `@Ident`
+	Key        string         `( "," @Ident )?`
+	Reference  *Expr          `":""=""range" @@`
 	Statements []*Statement   `"{" @@* "}"`
+	Else       []*Statement   `( "else" "{" @@* "}" )?`
+}
+
+type IfStatement struct {
+	Pos        lexer.Position
+	Condition  *Expr          `"if" @@`
+	Statements []*Statement   `"{" @@* "}"`
+	Else       *ElseStatement `( "else" @@ )?`
+}
+
+type ElseStatement struct {
+	If         *IfStatement `@@`
+	Statements []*Statement `| "{" @@* "}"`
 }
 
 type Statement struct {
@@ -50,24 +71,32 @@ type ReturnStatement struct {
 	Tags  []*Tag
 }
 
+// Attribute is key="value", key={ref}, a boolean attribute without a value or {...ref} which spreads the
+// attributes in a map into the element.
 type Attribute struct {
-	Pos   lexer.Position
-	Key   string   `@":"? @Ident ( @"-" @Ident )*`
-	Value *Literal `"=" @@`
+	Pos    lexer.Position
+	Spread *Expr    `  "{" "." "." "." @@ "}"`
+	Key    string   `| @":"? @Ident ( @"-" @Ident )*`
+	Value  *Literal `  ( "=" @@ )?`
 }
 
 type KV struct {
 	Pos   lexer.Position
 	Key   string `@String`
-	Value string `":" @"!"? @Ident ( @"." @Ident )*`
+	Value *Expr  `":" @@`
 }
 
+// Literal is a value in the template, Text is unquoted text with {refs} in it and Raw is the text of a
+// raw text element, comment or doctype which is written as is.
 type Literal struct {
-	Pos lexer.Position
-	Str *string       `@String`
-	Ref *string       `| "{" @Ident ( @"." @Ident )* "}"`
-	KV  []*KV         `| "{" [ @@ { "," @@ } ] "}"`
-	For *ForStatement `| @@`
+	Pos  lexer.Position
+	Str  *string       `@String`
+	KV   []*KV         `| "{" [ @@ { "," @@ } ] "}"`
+	Ref  *Expr         `| "{" @@ "}"`
+	For  *ForStatement `| @@`
+	If   *IfStatement  `| @@`
+	Text *string
+	Raw  *string
 }
 
 func (l *Literal) Clone() *Literal {
@@ -79,45 +108,108 @@ func (l *Literal) Clone() *Literal {
 		v := "" + *l.Str
 		newLiteral.Str = &v
 	}
-	if l.Ref != nil {
-		v := "" + *l.Ref
-		newLiteral.Ref = &v
-	}
+	newLiteral.Ref = l.Ref
+	newLiteral.Text = l.Text
+	newLiteral.Raw = l.Raw
 	if l.KV != nil {
 		newLiteral.KV = []*KV{}
 		for _, kv := range l.KV {
 			newLiteral.KV = append(newLiteral.KV, &KV{
 				Key:   "" + kv.Key,
-				Value: "" + kv.Value,
+				Value: kv.Value,
 			})
 		}
 	}
-	// TODO copy for
+	newLiteral.For = l.For.Clone()
+	newLiteral.If = l.If.Clone()
 	return newLiteral
 }
 
-var htmlParser = participle.MustBuild[Module]()
+func (s *ForStatement) Clone() *ForStatement {
+	if s == nil {
+		return nil
+	}
+	return &ForStatement{
+		Pos:        s.Pos,
+		Index:      s.Index,
+		Key:        s.Key,
+		Reference:  s.Reference,
+		Statements: cloneStatements(s.Statements),
+		Else:       cloneStatements(s.Else),
+	}
+}
+
+func (s *IfStatement) Clone() *IfStatement {
+	if s == nil {
+		return nil
+	}
+	newIf := &IfStatement{
+		Pos:        s.Pos,
+		Condition:  s.Condition,
+		Statements: cloneStatements(s.Statements),
+	}
+	if s.Else != nil {
+		newIf.Else = &ElseStatement{
+			If:         s.Else.If.Clone(),
+			Statements: cloneStatements(s.Else.Statements),
+		}
+	}
+	return newIf
+}
+
+func cloneStatements(statements []*Statement) []*Statement {
+	if statements == nil {
+		return nil
+	}
+	newStatements := []*Statement{}
+	for _, s := range statements {
+		newStatement := &Statement{}
+		if s.ReturnStatement != nil {
+			newStatement.ReturnStatement = &ReturnStatement{
+				Nodes: s.ReturnStatement.Nodes,
+				Tags:  cloneTags(s.ReturnStatement.Tags),
+			}
+		}
+		newStatements = append(newStatements, newStatement)
+	}
+	return newStatements
+}
+
+var (
+	htmlParser    = participle.MustBuild[Module](participle.UseLookahead(4))
+	templateCache = sync.Map{}
+	entityRegex   = regexp.MustCompile(`&#?\w+;`)
+	rawTextRegex  = regexp.MustCompile(`<!--|<(script|style|textarea)(?:\s[^>]*)?>`)
+)
 
+// Tag is an element, component, text or fragment in the template. A fragment only renders its children,
+// it is written as <>...</> in templates and returned by components and statements.
 type Tag struct {
+	Pos         lexer.Position
 	Name        string
 	Text        *Literal
 	Attributes  []*Attribute
 	Children    []*Tag
 	SelfClosing bool
+	Fragment    bool
 }
 
 func (t *Tag) Clone() *Tag {
 	newTag := &Tag{
+		Pos:         t.Pos,
 		Name:        t.Name,
 		Text:        t.Text.Clone(),
 		Attributes:  []*Attribute{},
 		SelfClosing: t.SelfClosing,
+		Fragment:    t.Fragment,
 		Children:    []*Tag{},
 	}
 	for _, v := range t.Attributes {
 		newTag.Attributes = append(newTag.Attributes, &Attribute{
-			Key:   v.Key,
-			Value: v.Value.Clone(),
+			Pos:    v.Pos,
+			Spread: v.Spread,
+			Key:    v.Key,
+			Value:  v.Value.Clone(),
 		})
 	}
 	for _, child := range t.Children {
@@ -135,59 +227,60 @@ func cloneTags(tags []*Tag) []*Tag {
 }
 
 func RenderString(tags []*Tag) string {
-	s := ""
-	for _, t := range tags {
-		s += RenderTagString(t, "") + "\n"
-	}
-	return s
+	r := &renderer{buf: &bytes.Buffer{}}
+	r.tags(tags, "")
+	return r.buf.String()
 }
 
 func RenderTagString(x *Tag, space string) string {
-	if x.Name == "" {
-		if x.Text != nil && x.Text.Str != nil {
-			return space + strings.ReplaceAll(*x.Text.Str, `"`, "")
-		}
-		if x.Text != nil && x.Text.Ref != nil {
-			return space + "{" + *x.Text.Ref + "}"
-		}
-	}
-	if x.Name == "fragment" {
-		s := ""
-		for _, c := range x.Children {
-			s += RenderTagString(c, space) + "\n"
-		}
-		return s
-	}
-	s := space + "<" + x.Name
-	for _, a := range x.Attributes {
-		if a.Value.Str != nil && *a.Value.Str != "" {
-			s += " " + a.Key + `="` + *a.Value.Str + `"`
-		}
-	}
-	if x.SelfClosing {
-		s += " />"
-	} else {
-		s += ">\n"
-	}
-	if !x.SelfClosing {
-		for _, c := range x.Children {
-			s += RenderTagString(c, space+"  ") + "\n"
-		}
-		s += space + "</" + x.Name + ">"
-	}
-	return s
+	r := &renderer{buf: &bytes.Buffer{}}
+	r.tag(x, space)
+	return r.buf.String()
 }
 
 func processTree(nodes []*AstNode) []*Tag {
 	tags := []*Tag{}
 	var prevTag *Tag
 	stack := stack.New[*Tag]()
+	appendTag := func(newTag *Tag) {
+		if prevTag != nil {
+			prevTag.Children = append(prevTag.Children, newTag)
+		} else {
+			tags = append(tags, newTag)
+		}
+	}
 	for _, n := range nodes {
-		if n.Open != nil {
+		if n.Comment != nil {
+			text := unquoteRaw(*n.Comment)
+			appendTag(&Tag{Pos: n.Pos, Name: "!--", Text: &Literal{Pos: n.Pos, Raw: &text}})
+		} else if n.Doctype != nil {
+			if !strings.EqualFold(n.Doctype[0], "doctype") {
+				panic(&posError{n.Pos, eris.Errorf("unknown declaration <!%s>", n.Doctype[0])})
+			}
+			text := strings.Join(n.Doctype[1:], " ")
+			appendTag(&Tag{Pos: n.Pos, Name: "!DOCTYPE", Text: &Literal{Pos: n.Pos, Raw: &text}})
+		} else if n.Raw != nil {
+			text := unquoteRaw(*n.Raw)
+			if prevTag != nil && (prevTag.Name == "script" || prevTag.Name == "style") {
+				appendTag(&Tag{Pos: n.Pos, Text: &Literal{Pos: n.Pos, Raw: &text}})
+			} else {
+				for _, entity := range entityRegex.FindAllString(text, -1) {
+					if html.UnescapeString(entity) == entity {
+						panic(&posError{n.Pos, eris.Errorf("unknown entity %s", entity)})
+					}
+				}
+				appendTag(&Tag{Pos: n.Pos, Text: &Literal{Pos: n.Pos, Text: &text}})
+			}
+		} else if n.Open != nil {
 			newTag := &Tag{
+				Pos:         n.Open.Pos,
 				Name:        n.Open.Name,
 				Attributes:  n.Open.Attributes,
-				SelfClosing: n.Open.SelfClose == "/",
+				SelfClosing: n.Open.SelfClose == "/" || lo.Contains(voidElements, n.Open.Name),
+				Fragment:    n.Open.Name == "",
+			}
+			if newTag.Fragment && len(newTag.Attributes) > 0 {
+				panic(&posError{n.Open.Pos, eris.New("fragments can't have attributes")})
 			}
 			if prevTag != nil {
 				prevTag.Children = append(prevTag.Children, newTag)
@@ -202,22 +295,27 @@ func processTree(nodes []*AstNode) []*Tag {
 				}
 			}
 		} else if n.Close != nil {
-			if n.Close.Name == prevTag.Name {
+			if lo.Contains(voidElements, n.Close.Name) {
+				panic(&posError{n.Close.Pos, eris.Errorf("void element <%s> can't have a closing tag", n.Close.Name)})
+			} else if prevTag == nil {
+				panic(&posError{n.Close.Pos, eris.Errorf("closing tag </%s> has no opening tag", n.Close.Name)})
+			} else if n.Close.Name == prevTag.Name {
 				prevTag, _ = stack.Pop()
 			} else {
-				panic(eris.Errorf("Brackets not matching for tag %s in line %d:%d, prevTag: %s", n.Close.Name, n.Close.Pos.Line, n.Close.Pos.Column, prevTag.Name))
+				panic(&posError{n.Close.Pos, eris.Errorf("closing tag </%s> does not match <%s> opened at %d:%d", n.Close.Name, prevTag.Name, prevTag.Pos.Line, prevTag.Pos.Column)})
 			}
 		} else if n.Content != nil {
 			newTag := &Tag{
+				Pos:  n.Content.Pos,
 				Name: "",
 				Text: n.Content,
 			}
 			if n.Content.For != nil {
-				for _, s := range n.Content.For.Statements {
-					if s.ReturnStatement != nil {
-						s.ReturnStatement.Tags = processTree(s.ReturnStatement.Nodes)
-					}
-				}
+				processStatements(n.Content.For.Statements)
+				processStatements(n.Content.For.Else)
+			}
+			if n.Content.If != nil {
+				processIf(n.Content.If)
 			}
 			if prevTag != nil {
 				prevTag.Children = append(prevTag.Children, newTag)
@@ -226,14 +324,94 @@ func processTree(nodes []*AstNode) []*Tag {
 			}
 		}
 	}
+	if prevTag != nil {
+		panic(&posError{prevTag.Pos, eris.Errorf("unclosed <%s> opened at %d:%d", prevTag.Name, prevTag.Pos.Line, prevTag.Pos.Column)})
+	}
 	return tags
 }
 
+func processStatements(statements []*Statement) {
+	for _, s := range statements {
+		if s.ReturnStatement != nil {
+			s.ReturnStatement.Tags = processTree(s.ReturnStatement.Nodes)
+		}
+	}
+}
+
+func processIf(s *IfStatement) {
+	processStatements(s.Statements)
+	if s.Else != nil {
+		if s.Else.If != nil {
+			processIf(s.Else.If)
+		} else {
+			processStatements(s.Else.Statements)
+		}
+	}
+}
+
+// Parse parses the template into tags, the returned error is a *TemplateError with the name and
+// the position in the template.
+func Parse(name, s string) (tags []*Tag, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newTemplateError(name, s, r)
+		}
+	}()
+	ast, err := htmlParser.ParseString(name, quoteText(quoteRawText(s)))
+	if err != nil {
+		return nil, newTemplateError(name, s, err)
+	}
+	return processTree(ast.Nodes), nil
+}
+
+// rawBacktick replaces the backticks in raw text like the template literals of a script while it is quoted.
+const rawBacktick = "\uE000"
+
+// quoteRawText quotes the content of comments and raw text elements like script in backticks so that
+// it is parsed as a single raw string instead of as gsx.
+func quoteRawText(s string) string {
+	out := strings.Builder{}
+	last := 0
+	for _, loc := range rawTextRegex.FindAllStringSubmatchIndex(s, -1) {
+		if loc[0] < last || strings.HasSuffix(s[loc[0]:loc[1]], "/>") {
+			continue
+		}
+		end := "-->"
+		if loc[2] != -1 {
+			end = "</" + s[loc[2]:loc[3]]
+		}
+		i := strings.Index(s[loc[1]:], end)
+		if i == -1 {
+			continue
+		}
+		text := strings.ReplaceAll(s[loc[1]:loc[1]+i], "`", rawBacktick)
+		out.WriteString(s[last:loc[1]] + "`" + text + "`")
+		last = loc[1] + i
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
+
+// unquoteRaw returns the raw text quoted by quoteRawText with its backticks.
+func unquoteRaw(s string) string {
+	return strings.ReplaceAll(s[1:len(s)-1], rawBacktick, "`")
+}
+
 func parse(name, s string) []*Tag {
-	ast, err := htmlParser.ParseString(name, s)
+	tags, err := Parse(name, s)
 	if err != nil {
-		println("name", name)
 		panic(err)
 	}
-	return processTree(ast.Nodes)
+	return tags
+}
+
+// getTemplate returns the parsed tags of the template, parsing it only the first time it is seen.
+// The returned tags are shared between renders and must not be modified.
+func getTemplate(name, s string) []*Tag {
+	if v, ok := templateCache.Load(s); ok {
+		return v.([]*Tag)
+	}
+	tags := parse(name, s)
+	templateCache.Store(s, tags)
+	return tags
 }
@@ -83,3 +83,107 @@ func TestForLoop(t *testing.T) {
 `, "\n")
 	r.Equal(expected, actual)
 }
+
+func TestIfStatement(t *testing.T) {
+	r := require.New(t)
+	tags := parse("test", `
+		<div>
+		if !todo.Completed {
+			return (
+				<span>"active"</span>
+			)
+		} else if todo.Archived {
+			return (
+				<span>"archived"</span>
+			)
+		} else {
+			return (
+				<span>"completed"</span>
+			)
+		}
+		</div>
+	`)
+	r.Len(tags, 1)
+	r.Len(tags[0].Children, 1)
+	s := tags[0].Children[0].Text.If
+	r.NotNil(s)
+	r.Equal("!todo.Completed", s.Condition.String())
+	r.Equal("<span>\n  active\n</span>\n", RenderString(s.Statements[0].ReturnStatement.Tags))
+	r.NotNil(s.Else.If)
+	r.Equal("todo.Archived", s.Else.If.Condition.String())
+	r.Equal("<span>\n  archived\n</span>\n", RenderString(s.Else.If.Statements[0].ReturnStatement.Tags))
+	r.Equal("<span>\n  completed\n</span>\n", RenderString(s.Else.If.Else.Statements[0].ReturnStatement.Tags))
+
+	clone := tags[0].Clone().Children[0].Text.If
+	r.Equal(s.Condition, clone.Condition)
+	r.Equal(s.Else.If.Condition, clone.Else.If.Condition)
+	r.NotSame(s.Statements[0].ReturnStatement.Tags[0], clone.Statements[0].ReturnStatement.Tags[0])
+	r.Equal(RenderString(s.Else.If.Else.Statements[0].ReturnStatement.Tags), RenderString(clone.Else.If.Else.Statements[0].ReturnStatement.Tags))
+
+	tags = parse("test", `
+		<ul>
+		for i, v := range todos {
+			return (
+				<li>{v}</li>
+			)
+		} else {
+			return (
+				<li>"empty"</li>
+			)
+		}
+		</ul>
+	`)
+	loop := tags[0].Children[0].Text.For
+	r.NotNil(loop)
+	cloneLoop := tags[0].Clone().Children[0].Text.For
+	r.NotNil(cloneLoop)
+	r.Equal("i", cloneLoop.Index)
+	r.Equal("v", cloneLoop.Key)
+	r.Equal(loop.Reference, cloneLoop.Reference)
+	r.NotSame(loop.Statements[0].ReturnStatement.Tags[0], cloneLoop.Statements[0].ReturnStatement.Tags[0])
+	r.Equal(RenderString(loop.Statements[0].ReturnStatement.Tags), RenderString(cloneLoop.Statements[0].ReturnStatement.Tags))
+	r.Equal("<li>\n  empty\n</li>\n", RenderString(cloneLoop.Else[0].ReturnStatement.Tags))
+}
+
+func TestHtml(t *testing.T) {
+	r := require.New(t)
+	h := Context{data: M{"funcName": "TestHtml", "todo": "x"}}
+	actual := RenderString(h.Render(`
+		<!DOCTYPE html>
+		<!-- <div>{todo}</div> -->
+		<p>"a" &nbsp; &#169; <br> "b"</p>
+		<input type="text" name="q">
+		<div class="empty" />
+		<style>.a > p { color: red; }</style>
+		<script type="module">if (a < b && c) { console.log("</div>") }</script>
+		<script src="/app.js" />
+		<textarea name="text">{todo} &amp; "{todo}"
+</textarea>
+	`))
+	expected := strings.TrimLeft(`
+<!DOCTYPE html>
+<!-- <div>{todo}</div> -->
+<p>
+  a
+  &nbsp; &#169;
+  <br />
+  b
+</p>
+<input type="text" name="q" />
+<div class="empty">
+</div>
+<style>.a > p { color: red; }</style>
+<script type="module">if (a < b && c) { console.log("</div>") }</script>
+<script src="/app.js"></script>
+<textarea name="text">x &amp; "x"
+</textarea>
+`, "\n")
+	r.Equal(expected, actual)
+
+	_, err := Parse("test", `<p>&nbssp;</p>`)
+	r.EqualError(err, "test template 1:4: unknown entity &nbssp;")
+	_, err = Parse("test", `<p><br></br></p>`)
+	r.EqualError(err, "test template 1:8: void element <br> can't have a closing tag")
+	actual = RenderString(h.Render("<script>const msg = `hello ${name}`;</script>\n<!-- use `gsx` -->"))
+	r.Equal("<script>const msg = `hello ${name}`;</script>\n<!-- use `gsx` -->\n", actual)
+}
@@ -0,0 +1,182 @@
+package gsx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/samber/lo"
+)
+
+var (
+	// ValidateProps validates the props struct of a component after its attributes are bound,
+	// gromer sets it to gromer.Validate so that fields can be marked with validate:"required".
+	ValidateProps func(props interface{}) error
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+)
+
+// propName returns the attribute name of the field from its json tag or the field name starting with a lower case letter.
+func propName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		name = strings.ToLower(f.Name[:1]) + f.Name[1:]
+	}
+	return name
+}
+
+// bindProps returns a new props struct of type t with the fields set from the attributes,
+// fields without an attribute are set from their default tag. A gsx.M field tagged gsx:"rest"
+// gets the attributes which don't match a field so that they can be spread into an element.
+func bindProps(t reflect.Type, props M) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	names := []string{}
+	rest := -1
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("json") == "-" {
+			continue
+		}
+		if f.Tag.Get("gsx") == "rest" {
+			if f.Type != reflect.TypeOf(M{}) {
+				return v, eris.Errorf("rest prop %s must be of type gsx.M", f.Name)
+			}
+			rest = i
+			continue
+		}
+		name := propName(f)
+		names = append(names, name)
+		value, ok := props[name]
+		if !ok {
+			def, ok := f.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			value = def
+		}
+		fv, err := convertProp(value, f.Type)
+		if err != nil {
+			return v, eris.Wrapf(err, "prop %s", name)
+		}
+		v.Field(i).Set(fv)
+	}
+	unknown := []string{}
+	for k := range props {
+		if !lo.Contains(names, k) {
+			unknown = append(unknown, k)
+		}
+	}
+	if rest != -1 {
+		v.Field(rest).Set(reflect.ValueOf(lo.PickByKeys(props, unknown)))
+	} else if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return v, eris.Errorf("unknown props %s, expected one of %s", strings.Join(unknown, ", "), strings.Join(names, ", "))
+	}
+	if ValidateProps != nil {
+		if err := ValidateProps(v.Interface()); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+// convertProp converts the value of an attribute to the type of the prop, strings are parsed and
+// numbers are converted to other number types.
+func convertProp(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if s, ok := value.(string); ok {
+		return parseProp(s, t)
+	}
+	if isNumber(v.Kind()) && isNumber(t.Kind()) {
+		return v.Convert(t), nil
+	}
+	if t.Kind() == reflect.String {
+		return reflect.ValueOf(fmt.Sprint(value)).Convert(t), nil
+	}
+	if t.Kind() == reflect.Slice && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+		items := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, err := convertProp(v.Index(i).Interface(), t.Elem())
+			if err != nil {
+				return items, err
+			}
+			items.Index(i).Set(item)
+		}
+		return items, nil
+	}
+	if t.Kind() == reflect.Ptr {
+		item, err := convertProp(value, t.Elem())
+		if err != nil {
+			return item, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(item)
+		return p, nil
+	}
+	return v, eris.Errorf("can't use %+v of type %s as %s", value, v.Type(), t)
+}
+
+func parseProp(s string, t reflect.Type) (reflect.Value, error) {
+	var value interface{}
+	var err error
+	switch {
+	case t == timeType:
+		value, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			value, err = time.Parse("2006-01-02", s)
+		}
+	case t == durationType:
+		value, err = time.ParseDuration(s)
+	case t.Kind() == reflect.String:
+		value = s
+	case t.Kind() == reflect.Bool:
+		value, err = strconv.ParseBool(s)
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		value, err = strconv.ParseInt(s, 10, t.Bits())
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr:
+		value, err = strconv.ParseUint(s, 10, t.Bits())
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		value, err = strconv.ParseFloat(s, t.Bits())
+	case t.Kind() == reflect.Slice:
+		items := reflect.MakeSlice(t, 0, 0)
+		for _, part := range strings.Split(s, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			item, err := parseProp(part, t.Elem())
+			if err != nil {
+				return items, err
+			}
+			items = reflect.Append(items, item)
+		}
+		return items, nil
+	case t.Kind() == reflect.Ptr:
+		item, err := parseProp(s, t.Elem())
+		if err != nil {
+			return item, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(item)
+		return p, nil
+	default:
+		return reflect.Zero(t), eris.Errorf("can't use %q as %s", s, t)
+	}
+	if err != nil {
+		return reflect.Zero(t), eris.Errorf("can't use %q as %s", s, t)
+	}
+	return reflect.ValueOf(value).Convert(t), nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
@@ -0,0 +1,90 @@
+package gsx
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type BadgeProps struct {
+	Label    string        `json:"label"`
+	Count    int           `json:"count" default:"1"`
+	Ratio    float32       `json:"ratio"`
+	Tags     []string      `json:"tags"`
+	Since    time.Time     `json:"since"`
+	Timeout  time.Duration `json:"timeout"`
+	Active   bool          `json:"active"`
+	Todo     *TodoData     `json:"todo"`
+	Size     uint8
+	Internal string `json:"-"`
+}
+
+func Badge(c *Context, p BadgeProps) []*Tag {
+	return c.Render(`
+		<span class={"active": active}>{label} ":" {count} {len(props.Tags)} {size}</span>
+	`)
+}
+
+func TestBindProps(t *testing.T) {
+	r := require.New(t)
+	todo := &TodoData{ID: "1"}
+	v, err := bindProps(reflect.TypeOf(BadgeProps{}), M{
+		"label":   "new",
+		"ratio":   "0.5",
+		"tags":    "a, b",
+		"since":   "2022-05-01",
+		"timeout": "1m30s",
+		"active":  "true",
+		"todo":    todo,
+		"size":    3,
+	})
+	r.NoError(err)
+	r.Equal(BadgeProps{
+		Label:   "new",
+		Count:   1,
+		Ratio:   0.5,
+		Tags:    []string{"a", "b"},
+		Since:   time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+		Timeout: 90 * time.Second,
+		Active:  true,
+		Todo:    todo,
+		Size:    3,
+	}, v.Interface())
+
+	v, err = bindProps(reflect.TypeOf(BadgeProps{}), M{"count": int64(5), "tags": []interface{}{"x", 1}})
+	r.NoError(err)
+	r.Equal(5, v.Interface().(BadgeProps).Count)
+	r.Equal([]string{"x", "1"}, v.Interface().(BadgeProps).Tags)
+
+	_, err = bindProps(reflect.TypeOf(BadgeProps{}), M{"count": "many"})
+	r.EqualError(err, `prop count: can't use "many" as int`)
+	_, err = bindProps(reflect.TypeOf(BadgeProps{}), M{"lable": "new", "internal": "x"})
+	r.EqualError(err, "unknown props internal, lable, expected one of label, count, ratio, tags, since, timeout, active, todo, size")
+}
+
+func TestPropsComponent(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(Badge, nil)
+	ValidateProps = func(props interface{}) error {
+		if props.(BadgeProps).Label == "" {
+			return errors.New("label is required")
+		}
+		return nil
+	}
+	defer func() {
+		ValidateProps = nil
+	}()
+	h := Context{
+		data: M{
+			"funcName": "TestPropsComponent",
+			"tags":     []string{"a", "b"},
+		},
+	}
+	actual := RenderString(h.Render(`<Badge label="new" active={true} tags={tags} size="2" />`))
+	r.Equal("<span class=\"active\">\n  new\n  :\n  1 2 2\n</span>\n", actual)
+	_, err := h.RenderE(`<Badge count="2" />`)
+	r.EqualError(errors.Unwrap(err), "component Badge: label is required")
+}
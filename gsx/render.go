@@ -0,0 +1,158 @@
+package gsx
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/samber/lo"
+)
+
+var (
+	// Minify makes Write render the tags without indentation and newlines, gromer enables it in production.
+	Minify = false
+	// StripComments makes Write leave out the html comments in the templates, gromer enables it in production.
+	StripComments = false
+	bufferPool    = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+)
+
+// the buffered output is written to the writer when it grows larger than flushSize.
+const flushSize = 32 * 1024
+
+type renderer struct {
+	w             io.Writer
+	buf           *bytes.Buffer
+	minify        bool
+	stripComments bool
+	// inline is whether the last node written was text or an inline element.
+	inline bool
+	// pre is whether the nodes are in a pre element where whitespace is part of the content.
+	pre bool
+}
+
+// flush writes the buffered output and flushes the writer if it is a http.Flusher.
+func (r *renderer) flush() {
+	r.w.Write(r.buf.Bytes())
+	r.buf.Reset()
+	if f, ok := r.w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+func (r *renderer) tags(tags []*Tag, space string) {
+	for _, t := range tags {
+		if t.Name == "!--" && r.stripComments {
+			continue
+		}
+		if t.Fragment {
+			r.tags(t.Children, space)
+			continue
+		}
+		// the whitespace between text and inline elements is rendered by the browser so it is kept as a
+		// single space when minified or in pre.
+		inline := isInline(t)
+		if (r.minify || r.pre) && inline && r.inline {
+			r.buf.WriteByte(' ')
+		}
+		r.tag(t, space)
+		r.newline()
+		if t.Name != "!--" {
+			r.inline = inline
+		}
+	}
+}
+
+func isInline(t *Tag) bool {
+	return t.Name == "" || lo.Contains(inlineElements, t.Name)
+}
+
+func (r *renderer) newline() {
+	if !r.minify && !r.pre {
+		r.buf.WriteByte('\n')
+	}
+}
+
+func (r *renderer) indent(space string) {
+	if !r.minify && !r.pre {
+		r.buf.WriteString(space)
+	}
+}
+
+func (r *renderer) tag(x *Tag, space string) {
+	if x.Name == "" {
+		if x.Text != nil && x.Text.Str != nil {
+			r.indent(space)
+			r.buf.WriteString(removeQuotes(*x.Text.Str))
+			return
+		}
+		if x.Text != nil && x.Text.Text != nil {
+			r.indent(space)
+			r.buf.WriteString(*x.Text.Text)
+			return
+		}
+		if x.Text != nil && x.Text.Raw != nil {
+			r.indent(space)
+			r.buf.WriteString(*x.Text.Raw)
+			return
+		}
+		if x.Text != nil && x.Text.Ref != nil {
+			r.indent(space)
+			r.buf.WriteString("{" + x.Text.Ref.String() + "}")
+			return
+		}
+	}
+	if x.Fragment {
+		r.tags(x.Children, space)
+		return
+	}
+	if x.Name == "!--" {
+		r.indent(space)
+		r.buf.WriteString("<!--" + *x.Text.Raw + "-->")
+		return
+	}
+	if x.Name == "!DOCTYPE" {
+		r.indent(space)
+		r.buf.WriteString("<!DOCTYPE " + *x.Text.Raw + ">")
+		return
+	}
+	r.indent(space)
+	r.buf.WriteString("<" + x.Name)
+	for _, a := range x.Attributes {
+		if a.Value == nil {
+			r.buf.WriteString(" " + a.Key)
+		} else if a.Value.Str != nil && *a.Value.Str != "" {
+			r.buf.WriteString(" " + a.Key + `="` + *a.Value.Str + `"`)
+		}
+	}
+	// html elements which aren't void can't be self closing so they are written with a closing tag.
+	if x.SelfClosing && (lo.Contains(voidElements, x.Name) || !lo.Contains(htmlElements, x.Name)) {
+		r.buf.WriteString(" />")
+		return
+	}
+	r.buf.WriteString(">")
+	if lo.Contains(rawTextElements, x.Name) {
+		// the content of raw text elements is written as is as whitespace is part of the text.
+		for _, child := range x.Children {
+			r.tag(child, "")
+		}
+		r.buf.WriteString("</" + x.Name + ">")
+		return
+	}
+	// the content of pre is written without indentation and newlines which would be shown.
+	pre := r.pre
+	r.pre = pre || x.Name == "pre"
+	r.newline()
+	r.inline = false
+	r.tags(x.Children, space+"  ")
+	r.indent(space)
+	r.buf.WriteString("</" + x.Name + ">")
+	r.pre = pre
+	if r.w != nil && r.buf.Len() > flushSize {
+		r.w.Write(r.buf.Bytes())
+		r.buf.Reset()
+	}
+}
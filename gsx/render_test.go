@@ -0,0 +1,164 @@
+package gsx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type flushWriter struct {
+	bytes.Buffer
+	flushed []string
+}
+
+func (w *flushWriter) Flush() {
+	w.flushed = append(w.flushed, w.String())
+}
+
+func TestWrite(t *testing.T) {
+	r := require.New(t)
+	c := NewContext(context.Background(), nil)
+	c.Set("funcName", "TestWrite")
+	c.AddMeta("title", "Todos")
+	tags := c.Render(`<ul><li>"one"</li></ul>`)
+	w := &flushWriter{}
+	Write(c, w, tags)
+	r.Len(w.flushed, 1)
+	r.True(strings.HasSuffix(w.flushed[0], "<body _='on htmx:error(errorInfo) put errorInfo.xhr.response into #error'>\n"))
+	r.Contains(w.flushed[0], "<title>Todos</title>")
+	r.Equal(w.flushed[0]+"<ul>\n  <li>\n    one\n  </li>\n</ul>\n  </body>\n</html>", w.String())
+
+	hx := NewContext(context.Background(), &HX{Target: "list"})
+	hx.Set("funcName", "TestWrite")
+	w = &flushWriter{}
+	Write(hx, w, tags)
+	r.Len(w.flushed, 0)
+	r.Equal("<ul>\n  <li>\n    one\n  </li>\n</ul>\n", w.String())
+
+	Minify = true
+	StripComments = true
+	defer func() {
+		Minify = false
+		StripComments = false
+	}()
+	w = &flushWriter{}
+	Write(hx, w, hx.Render(`<ul><!-- items --><li class="item">"one" {1 + 1}</li><br></ul>`))
+	r.Equal(`<ul><li class="item">one 2</li><br /></ul>`, w.String())
+}
+
+func TestMinifyWhitespace(t *testing.T) {
+	r := require.New(t)
+	Minify = true
+	defer func() {
+		Minify = false
+	}()
+	c := NewContext(context.Background(), &HX{})
+	c.Set("funcName", "TestMinifyWhitespace")
+	c.Set("count", 2)
+	w := &bytes.Buffer{}
+	Write(c, w, c.Render(`
+		<div>
+			<span class="todo-count"><strong>{count}</strong> items left</span>
+			<ul>
+				<li>"one"</li>
+				<li>"two"</li>
+			</ul>
+			<a href="/">"All"</a>
+			<a href="/active">"Active"</a>
+		</div>
+	`))
+	r.Equal(`<div><span class="todo-count"><strong>2</strong> items left</span><ul><li>one</li><li>two</li></ul><a href="/">All</a> <a href="/active">Active</a></div>`, w.String())
+}
+
+func TestPre(t *testing.T) {
+	r := require.New(t)
+	c := NewContext(context.Background(), &HX{})
+	c.Set("funcName", "TestPre")
+	c.Set("count", 2)
+	w := &bytes.Buffer{}
+	Write(c, w, c.Render(`
+		<div>
+			<pre>
+				<code class="go">{count}</code>
+				<b>"items"</b>
+			</pre>
+		</div>
+	`))
+	r.Equal("<div>\n  <pre><code class=\"go\">2</code> <b>items</b></pre>\n</div>\n", w.String())
+}
+
+func TestHead(t *testing.T) {
+	r := require.New(t)
+	c := NewContext(context.Background(), nil)
+	c.Set("funcName", "TestHead")
+	c.Meta(M{"title": "Todos & more", "description": `"Fast" todos`})
+	c.AddMeta("description", "Todos")
+	c.Canonical("https://example.com/todos?page=1")
+	c.Canonical("https://example.com/todos")
+	c.Preload("/fonts/inter.woff2", "font", "font/woff2")
+	c.Link("stylesheet", "/css/app.css", "", "")
+	c.Script("/js/htmx.js", false)
+	c.Script("/js/hyperscript.js", false)
+	c.AddScript(Script{Src: "/js/app.js", Module: true, Async: true, Integrity: "sha384-abc", CrossOrigin: "anonymous"})
+	c.Script("/js/htmx.js", true)
+	c.OpenGraph(OpenGraph{Title: "Todos", Type: "website", Image: "https://example.com/og.png"})
+	c.Twitter(TwitterCard{Card: "summary_large_image", Site: "@gromer"})
+	c.JSONLD(M{"@context": "https://schema.org", "@type": "WebSite", "name": "</script>"})
+	w := &bytes.Buffer{}
+	c.getHead().write(w)
+	r.Equal(`    <title>Todos &amp; more</title>
+    <meta name="description" content="Todos">
+    <meta property="og:title" content="Todos">
+    <meta property="og:type" content="website">
+    <meta property="og:image" content="https://example.com/og.png">
+    <meta name="twitter:card" content="summary_large_image">
+    <meta name="twitter:site" content="@gromer">
+    <link rel="canonical" href="https://example.com/todos">
+    <link rel="preload" href="/fonts/inter.woff2" type="font/woff2" as="font" crossorigin>
+    <link rel="stylesheet" href="/css/app.css">
+    <style></style>
+    <script src="/js/htmx.js" defer></script>
+    <script src="/js/hyperscript.js"></script>
+    <script src="/js/app.js" type="module" async integrity="sha384-abc" crossorigin="anonymous"></script>
+    <script type="application/ld+json">{"@context":"https://schema.org","@type":"WebSite","name":"\u003c/script\u003e"}</script>
+`, w.String())
+}
+
+func TestWriteLargeList(t *testing.T) {
+	r := require.New(t)
+	h := benchContext()
+	todos := []*TodoData{}
+	for i := 0; i < 2000; i++ {
+		todos = append(todos, &TodoData{ID: "id", Text: "My todo"})
+	}
+	h.Set("todos", todos)
+	h.hx = &HX{}
+	tags := h.Render(benchTemplate)
+	writes := 0
+	w := writerFunc(func(p []byte) (int, error) {
+		writes++
+		return len(p), nil
+	})
+	Write(h, w, tags)
+	r.Greater(writes, 1)
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func BenchmarkWrite(b *testing.B) {
+	h := benchContext()
+	h.hx = &HX{}
+	tags := h.Render(benchTemplate)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Write(h, io.Discard, tags)
+	}
+}
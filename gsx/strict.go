@@ -0,0 +1,227 @@
+package gsx
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+)
+
+var (
+	// StrictStyles makes gromer log the StyleWarnings of the registered components when it starts, it is
+	// enabled in development.
+	StrictStyles = false
+	// templates has the templates rendered or compiled for the component and page functions by name.
+	templates   = sync.Map{}
+	pageStyles  = sync.Map{}
+	classRegex  = regexp.MustCompile(`class=("[^"]*"|\{[^}]*\})`)
+	stringRegex = regexp.MustCompile(`"([^"]*)"`)
+)
+
+type templateKey struct {
+	name string
+	tpl  string
+}
+
+func addTemplate(name, tpl string) {
+	if name != "" {
+		templates.LoadOrStore(templateKey{name, tpl}, true)
+	}
+}
+
+// StyleWarnings returns the unknown utilities and variants in the styles of the components and pages, and
+// the style keys which are not used by a class in the templates of the component or page. The templates are
+// known when they are compiled by cmd/gsx or have been rendered, the keys of components whose templates
+// aren't known or have a class which can't be read like class={cls} are not reported.
+func StyleWarnings() []string {
+	styles := map[string]M{}
+	for name, comp := range compMap {
+		if comp.Styles != nil {
+			styles[name] = comp.Styles
+		}
+	}
+	pageStyles.Range(func(k, v interface{}) bool {
+		styles[k.(string)] = v.(M)
+		return true
+	})
+	names := lo.Keys(styles)
+	sort.Strings(names)
+	warnings := []string{}
+	for _, name := range names {
+		warnings = append(warnings, unknownUtilities(name, styles[name], "")...)
+		if classes, ok := templateClasses(name); ok {
+			warnings = append(warnings, unusedStyles(name, styles[name], "", classes)...)
+		}
+	}
+	return warnings
+}
+
+// TestingT is the part of testing.TB used by AssertStyles, it is an interface so that gsx doesn't import testing.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertStyles fails the test with the StyleWarnings, the components should be rendered in the test before
+// it unless their templates are compiled.
+//
+//	func TestStyles(t *testing.T) {
+//		gsx.AssertStyles(t)
+//	}
+func AssertStyles(t TestingT) {
+	t.Helper()
+	for _, w := range StyleWarnings() {
+		t.Errorf("%s", w)
+	}
+}
+
+func unknownUtilities(name string, styles M, parent string) []string {
+	warnings := []string{}
+	keys := lo.Keys(styles)
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := strings.TrimPrefix(parent+"."+k, ".")
+		switch it := styles[k].(type) {
+		case string:
+			for _, c := range strings.Fields(it) {
+				if c == "group" || c == "peer" {
+					continue
+				}
+				parts := splitVariants(c)
+				for _, v := range parts[:len(parts)-1] {
+					if !isVariant(v) {
+						warnings = append(warnings, fmt.Sprintf("%s: unknown variant '%s' in style '%s'%s", name, v, key, didYouMean(v, variantNames())))
+					}
+				}
+				utility := parts[len(parts)-1]
+				if _, err := getUtility(utility); err != nil {
+					warnings = append(warnings, fmt.Sprintf("%s: unknown utility '%s' in style '%s'%s", name, utility, key, didYouMean(utility, lo.Keys(twClassLookup))))
+				}
+			}
+		case M:
+			warnings = append(warnings, unknownUtilities(name, it, key)...)
+		}
+	}
+	return warnings
+}
+
+func unusedStyles(name string, styles M, parent string, classes map[string]bool) []string {
+	warnings := []string{}
+	keys := lo.Keys(styles)
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := strings.TrimPrefix(parent+"."+k, ".")
+		class := k
+		if k == "container" && parent != "" {
+			class = parent[strings.LastIndex(parent, ".")+1:]
+		} else if k == "container" && name != "" {
+			class = name
+		}
+		switch it := styles[k].(type) {
+		case string:
+			if !classes[class] {
+				warnings = append(warnings, fmt.Sprintf("%s: style '%s' is not used by a class in the template", name, key))
+			}
+		case M:
+			warnings = append(warnings, unusedStyles(name, it, key, classes)...)
+		}
+	}
+	return warnings
+}
+
+// templateClasses returns the classes in the templates of the function, it is false when there are no templates
+// or a class is an expression like class={cls} which can't be read.
+func templateClasses(name string) (map[string]bool, bool) {
+	classes := map[string]bool{}
+	found, dynamic := false, false
+	templates.Range(func(k, _ interface{}) bool {
+		key := k.(templateKey)
+		if key.name != name {
+			return true
+		}
+		found = true
+		for _, m := range classRegex.FindAllStringSubmatch(key.tpl, -1) {
+			value := m[1]
+			if strings.HasPrefix(value, "{") {
+				literals := stringRegex.FindAllStringSubmatch(value, -1)
+				if len(literals) == 0 {
+					dynamic = true
+				}
+				for _, l := range literals {
+					for _, c := range strings.Fields(l[1]) {
+						classes[c] = true
+					}
+				}
+				continue
+			}
+			for _, c := range strings.Fields(strings.Trim(value, `"`)) {
+				classes[c] = true
+			}
+		}
+		return true
+	})
+	return classes, found && !dynamic
+}
+
+func isVariant(v string) bool {
+	if _, ok := pseudoClasses[strings.TrimPrefix(strings.TrimPrefix(v, "group-"), "peer-")]; ok {
+		return true
+	}
+	if _, ok := pseudoElements[v]; ok {
+		return true
+	}
+	_, ok := lo.Find(Breakpoints, func(b Breakpoint) bool { return b.Name == v })
+	return ok || v == "dark"
+}
+
+func variantNames() []string {
+	names := []string{"dark"}
+	for k := range pseudoClasses {
+		names = append(names, k, "group-"+k, "peer-"+k)
+	}
+	for k := range pseudoElements {
+		names = append(names, k)
+	}
+	for _, b := range Breakpoints {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// didYouMean returns the closest of the names to v when it is close enough to be a typo.
+func didYouMean(v string, names []string) string {
+	best, distance := "", len(v)/3+2
+	sort.Strings(names)
+	for _, n := range names {
+		if d := levenshtein(v, n); d < distance {
+			best, distance = n, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean '%s'?", best)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = lo.Min([]int{prev[j] + 1, cur[j-1] + 1, prev[j-1] + cost})
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
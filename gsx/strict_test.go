@@ -0,0 +1,82 @@
+package gsx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+)
+
+func Panel(c *Context, title string) []*Tag {
+	return c.Render(`
+		<div class="Panel">
+			<h2 class={"title": true, "muted": false}>{title}</h2>
+			<p class="body text-lg">"card"</p>
+		</div>
+	`)
+}
+
+func Chip(c *Context, label string) []*Tag {
+	c.Set("cls", "chip")
+	return c.Render(`<span class={cls}>{label}</span>`)
+}
+
+func TestStyleWarnings(t *testing.T) {
+	r := require.New(t)
+	RegisterComponent(Panel, M{
+		"container": "flex flex-cols shadow-lg",
+		"title":     "text-2xl hoverr:text-red-500 sm:focus:underline",
+		"body":      "p-2 dark:bg-gray-8000",
+		"footer":    "border-t",
+		"actions": M{
+			"container": "flex",
+			"button":    "rounded",
+		},
+	}, "title")
+	RegisterComponent(Chip, M{"container": "rounded-ful", "unused": "p-1"}, "label")
+	warnings := func() []string {
+		return lo.Filter(StyleWarnings(), func(w string, _ int) bool {
+			return strings.HasPrefix(w, "Panel:") || strings.HasPrefix(w, "Chip:")
+		})
+	}
+	r.Equal([]string{
+		"Chip: unknown utility 'rounded-ful' in style 'container', did you mean 'rounded-full'?",
+		"Panel: unknown utility 'bg-gray-8000' in style 'body', did you mean 'bg-gray-800'?",
+		"Panel: unknown utility 'flex-cols' in style 'container', did you mean 'flex-col'?",
+		"Panel: unknown variant 'hoverr' in style 'title', did you mean 'hover'?",
+	}, warnings())
+
+	c := NewContext(context.Background(), nil)
+	c.Set("funcName", "TestStyleWarnings")
+	c.Render(`<Panel title="Hello" /><Chip label="new" />`)
+	r.Equal([]string{
+		"Chip: unknown utility 'rounded-ful' in style 'container', did you mean 'rounded-full'?",
+		"Panel: unknown utility 'bg-gray-8000' in style 'body', did you mean 'bg-gray-800'?",
+		"Panel: unknown utility 'flex-cols' in style 'container', did you mean 'flex-col'?",
+		"Panel: unknown variant 'hoverr' in style 'title', did you mean 'hover'?",
+		"Panel: style 'actions.button' is not used by a class in the template",
+		"Panel: style 'actions.container' is not used by a class in the template",
+		"Panel: style 'footer' is not used by a class in the template",
+	}, warnings())
+
+	rec := &testRecorder{}
+	AssertStyles(rec)
+	r.True(rec.helper)
+	r.Subset(rec.errors, warnings())
+}
+
+type testRecorder struct {
+	helper bool
+	errors []string
+}
+
+func (t *testRecorder) Helper() {
+	t.helper = true
+}
+
+func (t *testRecorder) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
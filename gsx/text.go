@@ -0,0 +1,186 @@
+package gsx
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+var (
+	forRegex = regexp.MustCompile(`^for\s+\w+(\s*,\s*\w+)?\s*:=\s*range\s`)
+	// the condition of an if can compare with < but can't have a tag in it so that text like if <b>... isn't a statement.
+	ifRegex = regexp.MustCompile(`^if\s(?:[^\n<]|<[^A-Za-z/>!\n])*\{\s*(\n|return)`)
+)
+
+// textScanner finds the unquoted text in the content of the template like Hello {user.Name}! and quotes it
+// in backticks so that it is parsed as a single text node with the {refs} in it. Tags, quoted strings,
+// refs and the for and if statements are copied as is.
+type textScanner struct {
+	s   string
+	i   int
+	out strings.Builder
+}
+
+func quoteText(s string) string {
+	t := &textScanner{s: s}
+	for t.i < len(t.s) {
+		t.content(0)
+		if t.i < len(t.s) {
+			t.copy(1)
+		}
+	}
+	return t.out.String()
+}
+
+func (t *textScanner) copy(n int) {
+	t.out.WriteString(t.s[t.i : t.i+n])
+	t.i += n
+}
+
+func (t *textScanner) peek() byte {
+	if t.i < len(t.s) {
+		return t.s[t.i]
+	}
+	return 0
+}
+
+func (t *textScanner) skipSpace() {
+	n := len(t.s[t.i:]) - len(strings.TrimLeft(t.s[t.i:], " \t\r\n"))
+	t.copy(n)
+}
+
+// end returns the index after the string, raw string or braces starting at i.
+func (t *textScanner) end(i int) int {
+	switch t.s[i] {
+	case '"':
+		for i++; i < len(t.s) && t.s[i] != '"' && t.s[i] != '\n'; i++ {
+			if t.s[i] == '\\' {
+				i++
+			}
+		}
+	case '`':
+		for i++; i < len(t.s) && t.s[i] != '`'; i++ {
+		}
+	case '{':
+		depth := 0
+		for ; i < len(t.s); i++ {
+			if t.s[i] == '"' {
+				i = t.end(i) - 1
+			} else if t.s[i] == '{' {
+				depth++
+			} else if t.s[i] == '}' {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+	}
+	return lo.Min([]int{i + 1, len(t.s)})
+}
+
+// content copies the content of an element or a return statement until the closer.
+func (t *textScanner) content(closer byte) {
+	for {
+		t.skipSpace()
+		switch ch := t.peek(); {
+		case ch == 0 || ch == closer || ch == '}' || ch == ')':
+			return
+		case ch == '<':
+			t.tag()
+		case ch == '"' || ch == '`':
+			t.copy(t.end(t.i) - t.i)
+		case ch == '{' && !t.textAfter(t.end(t.i)):
+			t.copy(t.end(t.i) - t.i)
+		case forRegex.MatchString(t.s[t.i:]) || ifRegex.MatchString(t.s[t.i:]):
+			t.statement()
+		default:
+			t.text()
+		}
+	}
+}
+
+// textAfter returns whether there is text or another ref after the ref on the same line so that it is part
+// of the text, the space between {first} {last} is kept in the text.
+func (t *textScanner) textAfter(i int) bool {
+	rest := strings.TrimLeft(t.s[i:], " \t")
+	return rest != "" && !strings.ContainsRune("<\"`\r\n", rune(rest[0]))
+}
+
+func (t *textScanner) tag() {
+	i := t.i + 1
+	for i < len(t.s) && t.s[i] != '>' {
+		if strings.ContainsRune("\"`{", rune(t.s[i])) {
+			i = t.end(i)
+		} else {
+			i++
+		}
+	}
+	t.copy(lo.Min([]int{i + 1, len(t.s)}) - t.i)
+}
+
+func (t *textScanner) text() {
+	i := t.i
+	for i < len(t.s) && !strings.ContainsRune("<`\n", rune(t.s[i])) {
+		if t.s[i] == '{' {
+			i = t.end(i)
+		} else {
+			i++
+		}
+	}
+	text := strings.TrimRight(t.s[t.i:i], " \t\r")
+	t.out.WriteString("`" + text + "`")
+	t.i += len(text)
+}
+
+// statement copies a for or if statement with its else branches and quotes the text in their return statements.
+func (t *textScanner) statement() {
+	for t.i < len(t.s) && t.s[t.i] != '{' {
+		if t.s[t.i] == '"' {
+			t.copy(t.end(t.i) - t.i)
+		} else {
+			t.copy(1)
+		}
+	}
+	if !t.block() || !strings.HasPrefix(strings.TrimLeft(t.s[t.i:], " \t\r\n"), "else") {
+		return
+	}
+	t.skipSpace()
+	t.copy(len("else"))
+	t.skipSpace()
+	if ifRegex.MatchString(t.s[t.i:]) {
+		t.statement()
+	} else {
+		t.block()
+	}
+}
+
+// block copies the { return (...) } of a statement and returns whether it is closed.
+func (t *textScanner) block() bool {
+	if t.peek() != '{' {
+		return false
+	}
+	t.copy(1)
+	for {
+		t.skipSpace()
+		if !strings.HasPrefix(t.s[t.i:], "return") {
+			break
+		}
+		t.copy(len("return"))
+		t.skipSpace()
+		if t.peek() != '(' {
+			break
+		}
+		t.copy(1)
+		t.content(')')
+		if t.peek() != ')' {
+			break
+		}
+		t.copy(1)
+	}
+	if t.peek() != '}' {
+		return false
+	}
+	t.copy(1)
+	return true
+}
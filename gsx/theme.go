@@ -0,0 +1,161 @@
+package gsx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"gopkg.in/yaml.v3"
+)
+
+// Theme extends or overrides the colors, spacing, breakpoints, fonts and shadows used by the styles, the
+// keys which are already there are replaced and the others are added.
+//
+//	colors:
+//	  brand:
+//	    500: "#1da1f2"
+//	  accent: "rgba(255, 0, 0, 1)"
+//	spacing:
+//	  "18": 4.5rem
+//	breakpoints:
+//	  - {name: sm, minWidth: 480px}
+//	fonts:
+//	  sans: Inter, sans-serif
+//	shadows:
+//	  card: 0 2px 8px rgba(0, 0, 0, 0.1)
+type Theme struct {
+	// Colors are like "brand-500" or a palette like "brand": {"500": "#1da1f2"}, hex colors can be used
+	// with opacity modifiers like bg-brand-500/50.
+	Colors M `json:"colors" yaml:"colors"`
+	// Spacing is the scale of the margin, padding and size utilities like p-18 and w-18.
+	Spacing MS `json:"spacing" yaml:"spacing"`
+	// Breakpoints replace the responsive variants when they are given.
+	Breakpoints []Breakpoint `json:"breakpoints" yaml:"breakpoints"`
+	// Fonts are the font families of the font utilities like font-sans.
+	Fonts MS `json:"fonts" yaml:"fonts"`
+	// Shadows are the box shadows of the shadow utilities like shadow-card, the empty key is shadow.
+	Shadows MS `json:"shadows" yaml:"shadows"`
+}
+
+// LoadTheme reads the theme from a json or yaml file.
+func LoadTheme(path string) (Theme, error) {
+	theme := Theme{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return theme, eris.Wrapf(err, "failed to read theme %s", path)
+	}
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(data, &theme)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &theme)
+	default:
+		return theme, eris.Errorf("theme %s should be a .json, .yaml or .yml file", path)
+	}
+	if err != nil {
+		return theme, eris.Wrapf(err, "failed to parse theme %s", path)
+	}
+	return theme, nil
+}
+
+// SetTheme applies the theme to the styles and the colors of GetColor, it should be called before the
+// components are registered and the server is started.
+func SetTheme(t Theme) error {
+	colorValues := MS{}
+	if err := flattenColors(colorValues, "", t.Colors); err != nil {
+		return err
+	}
+	for k, v := range t.Spacing {
+		if err := validateThemeValue("spacing", k, v); err != nil {
+			return err
+		}
+	}
+	for _, b := range t.Breakpoints {
+		if b.Name == "" || strings.Contains(b.Name, ":") {
+			return eris.Errorf("theme breakpoint '%s' is not a valid name", b.Name)
+		}
+		if err := validateThemeValue("breakpoint", b.Name, b.MinWidth); err != nil {
+			return err
+		}
+	}
+	for k, v := range t.Fonts {
+		if err := validateThemeValue("font", k, v); err != nil {
+			return err
+		}
+	}
+	for k, v := range t.Shadows {
+		if err := validateThemeValue("shadow", k, v); err != nil {
+			return err
+		}
+	}
+	for k, v := range colorValues {
+		colors.Values[k] = hexToRgba(v)
+	}
+	for k, v := range t.Spacing {
+		spacing.Values[k] = v
+		sizes.Values[k] = v
+	}
+	if len(t.Breakpoints) > 0 {
+		Breakpoints = t.Breakpoints
+	}
+	for k, v := range t.Fonts {
+		twClassLookup["font-"+k] = "font-family: " + v + ";"
+	}
+	for k, v := range t.Shadows {
+		if k == "" {
+			twClassLookup["shadow"] = "box-shadow: " + v + ";"
+		} else {
+			twClassLookup["shadow-"+k] = "box-shadow: " + v + ";"
+		}
+	}
+	mapApply(sizes)
+	mapApply(spacing)
+	colorApply(colors)
+	return nil
+}
+
+// flattenColors adds the colors of the palettes like "brand": {"500": "#1da1f2"} as brand-500.
+func flattenColors(values MS, prefix string, m map[string]interface{}) error {
+	for k, v := range m {
+		name := k
+		if prefix != "" {
+			name = prefix + "-" + k
+		}
+		switch it := v.(type) {
+		case string:
+			if !isColor(it) && it != "transparent" && it != "currentColor" {
+				return eris.Errorf("theme color '%s' has an invalid value '%s'", name, it)
+			}
+			values[name] = it
+		case M:
+			if err := flattenColors(values, name, it); err != nil {
+				return err
+			}
+		case map[string]interface{}:
+			if err := flattenColors(values, name, it); err != nil {
+				return err
+			}
+		case map[interface{}]interface{}:
+			palette := M{}
+			for pk, pv := range it {
+				palette[fmt.Sprint(pk)] = pv
+			}
+			if err := flattenColors(values, name, palette); err != nil {
+				return err
+			}
+		default:
+			return eris.Errorf("theme color '%s' should be a string or a palette but got %T", name, v)
+		}
+	}
+	return nil
+}
+
+func validateThemeValue(kind, k, v string) error {
+	if v == "" || strings.ContainsAny(v, ";{}") {
+		return eris.Errorf("theme %s '%s' has an invalid value '%s'", kind, k, v)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package gsx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTheme(t *testing.T) {
+	r := require.New(t)
+	oldColors, oldSpacing, oldSizes, oldBreakpoints, oldLookup := MS{}, MS{}, MS{}, Breakpoints, MS{}
+	for _, v := range []struct{ from, to MS }{{colors.Values, oldColors}, {spacing.Values, oldSpacing}, {sizes.Values, oldSizes}, {twClassLookup, oldLookup}} {
+		for k, val := range v.from {
+			v.to[k] = val
+		}
+	}
+	defer func() {
+		colors.Values, spacing.Values, sizes.Values, Breakpoints, twClassLookup = oldColors, oldSpacing, oldSizes, oldBreakpoints, oldLookup
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "theme.yaml")
+	r.NoError(os.WriteFile(path, []byte(`
+colors:
+  brand:
+    500: "#1da1f2"
+  red-500: "#f00"
+spacing:
+  "18": 4.5rem
+breakpoints:
+  - {name: tablet, minWidth: 600px}
+  - {name: desktop, minWidth: 1200px}
+fonts:
+  sans: Inter, sans-serif
+shadows:
+  card: 0 2px 8px rgba(0, 0, 0, 0.1)
+`), 0644))
+	theme, err := LoadTheme(path)
+	r.NoError(err)
+	r.NoError(SetTheme(theme))
+	r.Equal("rgba(29, 161, 242, 1)", GetColor("brand-500"))
+	r.Equal("rgba(255, 0, 0, 1)", GetColor("red-500"))
+	r.Equal("rgba(239, 68, 68, 1)", oldColors["red-500"])
+	r.Equal(`
+.Card {
+  --tw-bg-opacity: 1; background-color: rgba(29, 161, 242, var(--tw-bg-opacity));
+  padding: 4.5rem;
+  width: 4.5rem;
+  font-family: Inter, sans-serif;
+  box-shadow: 0 2px 8px rgba(0, 0, 0, 0.1);
+  border-color: rgba(255, 0, 0, 0.5);
+}
+
+@media (min-width: 600px) {
+  .Card {
+    display: flex;
+  }
+}
+
+@media (min-width: 1200px) {
+  .Card {
+    display: grid;
+  }
+}
+`, computeCss(M{"container": "bg-brand-500 p-18 w-18 font-sans shadow-card border-red-500/50 sm:block tablet:flex desktop:grid"}, "Card"))
+
+	path = filepath.Join(dir, "theme.json")
+	r.NoError(os.WriteFile(path, []byte(`{"colors": {"brand": {"600": "#1a91da"}}, "spacing": {"13": "3.25rem"}}`), 0644))
+	theme, err = LoadTheme(path)
+	r.NoError(err)
+	r.NoError(SetTheme(theme))
+	r.Equal("rgba(26, 145, 218, 1)", GetColor("brand-600"))
+	r.Equal("rgba(29, 161, 242, 1)", GetColor("brand-500"))
+	r.Equal("margin: 3.25rem;", twClassLookup["m-13"])
+
+	r.EqualError(SetTheme(Theme{Colors: M{"brand": M{"500": "blue;"}}}), "theme color 'brand-500' has an invalid value 'blue;'")
+	r.EqualError(SetTheme(Theme{Spacing: MS{"18": "1rem}"}}), "theme spacing '18' has an invalid value '1rem}'")
+	r.EqualError(SetTheme(Theme{Breakpoints: []Breakpoint{{Name: "sm"}}}), "theme breakpoint 'sm' has an invalid value ''")
+	_, err = LoadTheme(filepath.Join(dir, "theme.toml"))
+	r.Error(err)
+}
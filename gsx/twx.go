@@ -1,6 +1,31 @@
 package gsx
 
-import "strings"
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/samber/lo"
+)
+
+// Breakpoint is the min width of a responsive variant like sm:flex-row.
+type Breakpoint struct {
+	Name     string `json:"name" yaml:"name"`
+	MinWidth string `json:"minWidth" yaml:"minWidth"`
+}
+
+// Breakpoints are the responsive variants in the order they are written in the stylesheet, the rules of
+// the later ones override the earlier ones.
+var Breakpoints = []Breakpoint{
+	{"sm", "640px"},
+	{"md", "768px"},
+	{"lg", "1024px"},
+	{"xl", "1280px"},
+	{"2xl", "1536px"},
+}
 
 type KeyValues struct {
 	Keys   M
@@ -350,6 +375,21 @@ var twClassLookup = MS{
 	"flex-wrap":           "flex-wrap: wrap;",
 	"flex-nowrap":         "flex-wrap: nowrap;",
 	"flex-wrap-reverse":   "flex-wrap: wrap-reverse;",
+	"order-1":             "order: 1;",
+	"order-2":             "order: 2;",
+	"order-3":             "order: 3;",
+	"order-4":             "order: 4;",
+	"order-5":             "order: 5;",
+	"order-6":             "order: 6;",
+	"order-7":             "order: 7;",
+	"order-8":             "order: 8;",
+	"order-9":             "order: 9;",
+	"order-10":            "order: 10;",
+	"order-11":            "order: 11;",
+	"order-12":            "order: 12;",
+	"order-first":         "order: -9999;",
+	"order-last":          "order: 9999;",
+	"order-none":          "order: 0;",
 	"items-baseline":      "align-items: baseline;",
 	"items-start":         "align-items: flex-start;",
 	"items-center":        "align-items: center;",
@@ -485,10 +525,38 @@ var twClassLookup = MS{
 	"min-w-screen":        "width: 100vw;",
 }
 
+// opacityVars are the css variables of the opacity utilities like text-opacity-50 which change the
+// opacity of the color utilities like text-red-900 with the same prefix.
+var opacityVars = MS{
+	"bg":     "--tw-bg-opacity",
+	"text":   "--tw-text-opacity",
+	"border": "--tw-border-opacity",
+	"divide": "--tw-divide-opacity",
+	"ring":   "--tw-ring-opacity",
+}
+
+var opacities = MS{
+	"0":   "0",
+	"5":   "0.05",
+	"10":  "0.1",
+	"20":  "0.2",
+	"25":  "0.25",
+	"30":  "0.3",
+	"40":  "0.4",
+	"50":  "0.5",
+	"60":  "0.6",
+	"70":  "0.7",
+	"75":  "0.75",
+	"80":  "0.8",
+	"90":  "0.9",
+	"95":  "0.95",
+	"100": "1",
+}
+
 func init() {
 	mapApply(sizes)
 	mapApply(spacing)
-	mapApply(colors)
+	colorApply(colors)
 	mapApply(borders)
 	mapApply(radius)
 }
@@ -500,18 +568,206 @@ func mapApply(obj KeyValues) {
 			if vkey != "" {
 				suffix = "-" + vkey
 			}
-			className := key + suffix
-			if vstring, ok := v.(string); ok {
-				twClassLookup[className] = vstring + ": " + vv + ";"
+			twClassLookup[key+suffix] = declare(v, vv)
+		}
+	}
+}
+
+// colorApply adds the color utilities and the opacity utilities which change their opacity.
+func colorApply(obj KeyValues) {
+	for key := range obj.Keys {
+		for vkey, vv := range obj.Values {
+			twClassLookup[key+"-"+vkey] = colorDecl(key, vv, "")
+		}
+	}
+	for key, v := range opacityVars {
+		for okey, ov := range opacities {
+			twClassLookup[key+"-opacity-"+okey] = v + ": " + ov + ";"
+		}
+	}
+}
+
+// declare returns the declarations of the value for the property or the Arr of properties.
+func declare(props interface{}, value string) string {
+	if p, ok := props.(string); ok {
+		return p + ": " + value + ";"
+	}
+	decls := ""
+	for _, p := range props.(Arr) {
+		decls += p.(string) + ": " + value + ";"
+	}
+	return decls
+}
+
+// colorDecl returns the declaration of the color utility, the rgba colors use the opacity variable of the
+// utility like --tw-text-opacity unless the alpha of the color is given.
+func colorDecl(key, color, alpha string) string {
+	prop := colors.Keys[key].(string)
+	if !strings.HasPrefix(color, "rgba(") || !strings.HasSuffix(color, ", 1)") {
+		return prop + ": " + color + ";"
+	}
+	rgb := strings.TrimSuffix(color, "1)")
+	if alpha != "" {
+		return prop + ": " + rgb + alpha + ");"
+	}
+	v := opacityVars[strings.Split(key, "-")[0]]
+	return v + ": 1; " + prop + ": " + rgb + "var(" + v + "));"
+}
+
+// getUtility returns the declarations of a utility like flex-1, w-[37rem], bg-[#1da1f2] or bg-red-500/50,
+// the error says why an arbitrary value or opacity modifier is invalid.
+func getUtility(c string) (string, error) {
+	if decl, ok := twClassLookup[c]; ok {
+		return decl, nil
+	}
+	base, alpha := c, ""
+	if parts := splitOutside(c, '/'); len(parts) > 1 {
+		base, alpha = strings.Join(parts[:len(parts)-1], "/"), parts[len(parts)-1]
+		v, err := parseOpacity(alpha)
+		if err != nil {
+			return "", eris.Wrapf(err, "invalid opacity modifier in '%s'", c)
+		}
+		alpha = v
+	}
+	if i := strings.Index(base, "-["); i != -1 && strings.HasSuffix(base, "]") {
+		key, value := base[:i], strings.ReplaceAll(base[i+2:len(base)-1], "_", " ")
+		if value == "" || strings.ContainsAny(value, ";{}[]") {
+			return "", eris.Errorf("invalid arbitrary value in '%s'", c)
+		}
+		if _, ok := colors.Keys[key]; ok && (isColor(value) || alpha != "") {
+			if !isColor(value) {
+				return "", eris.Errorf("opacity modifier in '%s' needs a color", c)
 			}
-			if varr, ok := v.(Arr); ok {
-				twClassLookup[className] = ""
-				for _, kk := range varr {
-					twClassLookup[className] += kk.(string) + ": " + vv + ";"
+			color := hexToRgba(value)
+			if alpha != "" && !strings.HasSuffix(color, ", 1)") {
+				return "", eris.Errorf("opacity modifier in '%s' needs a hex or palette color", c)
+			}
+			return colorDecl(key, color, alpha), nil
+		}
+		if alpha != "" {
+			return "", eris.Errorf("opacity modifier in '%s' needs a color", c)
+		}
+		if key == "text" {
+			return "font-size: " + value + ";", nil
+		}
+		if key == "bg" && (strings.HasPrefix(value, "url(") || strings.Contains(value, "gradient(")) {
+			return "background-image: " + value + ";", nil
+		}
+		if key == "opacity" {
+			return "opacity: " + value + ";", nil
+		}
+		if v, ok := opacityVars[strings.TrimSuffix(key, "-opacity")]; ok && strings.HasSuffix(key, "-opacity") {
+			return v + ": " + value + ";", nil
+		}
+		for _, kv := range []KeyValues{sizes, spacing, borders, radius} {
+			if props, ok := kv.Keys[key]; ok {
+				return declare(props, value), nil
+			}
+		}
+		return "", eris.Errorf("unknown utility for arbitrary value '%s'", c)
+	}
+	if alpha != "" {
+		for key := range colors.Keys {
+			if color, ok := colors.Values[strings.TrimPrefix(base, key+"-")]; ok && strings.HasPrefix(base, key+"-") {
+				if !strings.HasSuffix(color, ", 1)") {
+					return "", eris.Errorf("opacity modifier in '%s' needs a palette color", c)
+				}
+				return colorDecl(key, color, alpha), nil
+			}
+		}
+		return "", eris.Errorf("opacity modifier in '%s' needs a color", c)
+	}
+	return "", eris.Errorf("unknown utility '%s'", c)
+}
+
+// isOpacityUtility returns whether the utility is like text-opacity-50, these are written after the color
+// utilities of the rule which set the opacity to 1.
+func isOpacityUtility(c string) bool {
+	i := strings.Index(c, "-opacity-")
+	return i != -1 && opacityVars[c[:i]] != ""
+}
+
+// parseOpacity returns the alpha of an opacity modifier like 50 or [0.35].
+func parseOpacity(v string) (string, error) {
+	if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		f, err := strconv.ParseFloat(v[1:len(v)-1], 64)
+		if err != nil || f < 0 || f > 1 {
+			return "", eris.Errorf("'%s' is not between 0 and 1", v)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > 100 {
+		return "", eris.Errorf("'%s' is not between 0 and 100", v)
+	}
+	return strconv.FormatFloat(float64(n)/100, 'f', -1, 64), nil
+}
+
+var hexColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func isColor(v string) bool {
+	return hexColorRegex.MatchString(v) || strings.HasPrefix(v, "rgb") || strings.HasPrefix(v, "hsl")
+}
+
+// hexToRgba converts a hex color like #1da1f2 to rgba so that its opacity can be changed.
+func hexToRgba(v string) string {
+	if !hexColorRegex.MatchString(v) {
+		return v
+	}
+	hex := v[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	n, _ := strconv.ParseUint(hex, 16, 32)
+	return fmt.Sprintf("rgba(%d, %d, %d, 1)", n>>16, n>>8&0xff, n&0xff)
+}
+
+// validateStyles checks the arbitrary values and opacity modifiers of the classes in the styles.
+func validateStyles(styles M) error {
+	for _, k := range lo.Keys(styles) {
+		switch it := styles[k].(type) {
+		case string:
+			for _, c := range strings.Fields(it) {
+				parts := splitVariants(c)
+				utility := parts[len(parts)-1]
+				if !strings.ContainsAny(utility, "[/") {
+					continue
 				}
+				if _, err := getUtility(utility); err != nil {
+					return eris.Wrapf(err, "invalid class '%s' of '%s'", c, k)
+				}
+			}
+		case M:
+			if err := validateStyles(it); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitVariants splits a class like hover:bg-[url(http://a.b/c.png)] by the colons which are not in brackets.
+func splitVariants(c string) []string {
+	return splitOutside(c, ':')
+}
+
+func splitOutside(c string, sep rune) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	for i, r := range c {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, c[start:i])
+				start = i + 1
 			}
 		}
 	}
+	return append(parts, c[start:])
 }
 
 func GetColor(k string) string {
@@ -529,42 +785,213 @@ func getClassName(parent, k string) string {
 	return "." + k
 }
 
+// stylesheet collects the css rules of the styles, the dark and responsive rules are written after the
+// other rules in the order of the breakpoints so that they override them.
+type stylesheet struct {
+	rules []*cssRule
+}
+
+type cssRule struct {
+	breakpoint int
+	dark       bool
+	selector   string
+	decls      []string
+	opacities  []string
+}
+
+// markers are the selectors of the classes with group and peer in the styles.
+type markers struct {
+	group string
+	peer  string
+}
+
 func computeCss(classMap M, parent string) string {
-	p := "\n"
-	for k, v := range classMap {
-		switch it := v.(type) {
+	s := &stylesheet{}
+	s.add(classMap, parent)
+	return s.String()
+}
+
+func (s *stylesheet) add(classMap M, parent string) {
+	s.addWith(classMap, parent, markers{".group", ".peer"})
+}
+
+func (s *stylesheet) addWith(classMap M, parent string, m markers) {
+	keys := lo.Keys(classMap)
+	sort.Strings(keys)
+	for _, k := range keys {
+		if it, ok := classMap[k].(string); ok {
+			classes := strings.Fields(it)
+			if lo.Contains(classes, "group") {
+				m.group = getClassName(parent, k)
+			}
+			if lo.Contains(classes, "peer") {
+				m.peer = getClassName(parent, k)
+			}
+		}
+	}
+	for _, k := range keys {
+		switch it := classMap[k].(type) {
 		case string:
 			className := getClassName(parent, k)
-			p += className + " {\n"
-			classes := strings.Split(it, " ")
-			for _, c := range classes {
-				if s, ok := twClassLookup[c]; ok {
-					p += "  " + s + "\n"
+			rules := []*cssRule{{selector: className}}
+			for _, c := range strings.Fields(it) {
+				v, utility, ok := parseVariants(className, c, m)
+				if !ok {
+					continue
 				}
-			}
-			p += "}\n"
-			for _, c := range classes {
-				if strings.Contains(c, ":") {
-					arr := strings.Split(c, ":")
-					prefix := arr[0]
-					class := arr[1]
-					if prefix == "placeholder" {
-						p += "\n" + className + "::placeholder" + " {\n"
-						if s, ok := twClassLookup[class]; ok {
-							p += "  " + s + "\n"
-						}
-						p += "}\n"
-					} else if prefix == "hover" {
-						p += "\n" + className + ":hover" + " {\n"
-						if s, ok := twClassLookup[class]; ok {
-							p += "  " + s + "\n"
-						}
-						p += "}\n"
-					}
+				decl, err := getUtility(utility)
+				if err != nil {
+					continue
+				}
+				rule, found := lo.Find(rules, func(r *cssRule) bool {
+					return r.breakpoint == v.breakpoint && r.dark == v.dark && r.selector == v.selector
+				})
+				if !found {
+					rule = &cssRule{breakpoint: v.breakpoint, dark: v.dark, selector: v.selector}
+					rules = append(rules, rule)
+				}
+				if isOpacityUtility(utility) {
+					rule.opacities = append(rule.opacities, decl)
+				} else {
+					rule.decls = append(rule.decls, decl)
 				}
 			}
+			s.rules = append(s.rules, rules...)
 		case M:
-			p += computeCss(it, k)
+			s.addWith(it, k, m)
+		}
+	}
+}
+
+// pseudoClasses are the state variants like hover:bg-red-500, they can be used with group- and peer-.
+var pseudoClasses = MS{
+	"hover":         ":hover",
+	"focus":         ":focus",
+	"focus-visible": ":focus-visible",
+	"focus-within":  ":focus-within",
+	"active":        ":active",
+	"disabled":      ":disabled",
+	"enabled":       ":enabled",
+	"checked":       ":checked",
+	"visited":       ":visited",
+	"first":         ":first-child",
+	"last":          ":last-child",
+	"odd":           ":nth-child(odd)",
+	"even":          ":nth-child(even)",
+}
+
+// pseudoElements are the variants which style a part of the element, they are always at the end of the selector.
+var pseudoElements = MS{
+	"placeholder": "::placeholder",
+	"before":      "::before",
+	"after":       "::after",
+	"selection":   "::selection",
+}
+
+// DarkMode is how the dark: variants are applied, "media" uses the color scheme of the system and "class"
+// uses a dark class on an ancestor like <html class="dark">.
+var DarkMode = "media"
+
+// variant is the media and selector of the rule of a class like dark:sm:hover:bg-gray-800.
+type variant struct {
+	breakpoint int
+	dark       bool
+	selector   string
+}
+
+// parseVariants returns the variant and utility of a class like sm:hover:bg-red-500, the breakpoint is the
+// position in Breakpoints starting from 1 and is 0 when there is none.
+func parseVariants(className, c string, m markers) (variant, string, bool) {
+	parts := splitVariants(c)
+	v := variant{}
+	classes, pseudo, prefix := "", "", ""
+	for _, p := range parts[:len(parts)-1] {
+		if _, i, ok := lo.FindIndexOf(Breakpoints, func(b Breakpoint) bool { return b.Name == p }); ok {
+			v.breakpoint = i + 1
+		} else if p == "dark" {
+			v.dark = true
+		} else if s, ok := pseudoClasses[p]; ok {
+			classes += s
+		} else if s, ok := pseudoElements[p]; ok {
+			pseudo = s
+		} else if s, ok := pseudoClasses[strings.TrimPrefix(p, "group-")]; ok && strings.HasPrefix(p, "group-") {
+			prefix = m.group + s + " "
+			className = relativeTo(m.group, className, true)
+		} else if s, ok := pseudoClasses[strings.TrimPrefix(p, "peer-")]; ok && strings.HasPrefix(p, "peer-") {
+			prefix = m.peer + s + " ~ "
+			className = relativeTo(m.peer, className, false)
+		} else {
+			return v, "", false
+		}
+	}
+	if v.dark && DarkMode == "class" {
+		prefix = ".dark " + prefix
+		v.dark = false
+	}
+	v.selector = prefix + className + classes + pseudo
+	return v, parts[len(parts)-1], true
+}
+
+// relativeTo returns the selector of the class under the group or next to the peer, the parent classes
+// the selectors have in common are written once like .Todo .row:hover .label.
+func relativeTo(marker, className string, descendant bool) string {
+	if descendant && strings.HasPrefix(className, marker+" ") {
+		return strings.TrimPrefix(className, marker+" ")
+	}
+	if i := strings.LastIndex(marker, " "); i != -1 && strings.HasPrefix(className, marker[:i+1]) {
+		return strings.TrimPrefix(className, marker[:i+1])
+	}
+	return className
+}
+
+func (r *cssRule) declarations() []string {
+	return append(append([]string{}, r.decls...), r.opacities...)
+}
+
+// media returns the media query of the rule.
+func (r *cssRule) media() string {
+	conditions := []string{}
+	if r.breakpoint != 0 {
+		conditions = append(conditions, "(min-width: "+Breakpoints[r.breakpoint-1].MinWidth+")")
+	}
+	if r.dark {
+		conditions = append(conditions, "(prefers-color-scheme: dark)")
+	}
+	return strings.Join(conditions, " and ")
+}
+
+func (s *stylesheet) String() string {
+	rules := append([]*cssRule{}, s.rules...)
+	order := func(r *cssRule) int {
+		if r.dark {
+			return r.breakpoint*2 + 1
+		}
+		return r.breakpoint * 2
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return order(rules[i]) < order(rules[j])
+	})
+	p := ""
+	for i, r := range rules {
+		media := r.media()
+		if media == "" {
+			p += "\n" + r.selector + " {\n"
+			for _, d := range r.declarations() {
+				p += "  " + d + "\n"
+			}
+			p += "}\n"
+			continue
+		}
+		if i == 0 || rules[i-1].media() != media {
+			p += "\n@media " + media + " {\n"
+		}
+		p += "  " + r.selector + " {\n"
+		for _, d := range r.declarations() {
+			p += "    " + d + "\n"
+		}
+		p += "  }\n"
+		if i == len(rules)-1 || rules[i+1].media() != media {
+			p += "}\n"
 		}
 	}
 	return p
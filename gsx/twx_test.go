@@ -0,0 +1,213 @@
+package gsx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeCss(t *testing.T) {
+	r := require.New(t)
+	css := computeCss(M{
+		"container": "flex lg:flex-col sm:flex-row p-2",
+		"link":      "hover:underline placeholder:text-gray-300 sm:hover:no-underline",
+		"bottom": M{
+			"section": "flex-1 order-3 sm:order-2 sm:min-w-min",
+		},
+	}, "Todos")
+	r.Equal(`
+.bottom .section {
+  flex: 1;
+  order: 3;
+}
+
+.Todos {
+  display: flex;
+  padding: 0.5rem;
+}
+
+.Todos .link {
+}
+
+.Todos .link:hover {
+  text-decoration: underline;
+}
+
+.Todos .link::placeholder {
+  --tw-text-opacity: 1; color: rgba(209, 213, 219, var(--tw-text-opacity));
+}
+
+@media (min-width: 640px) {
+  .bottom .section {
+    order: 2;
+    min-width: min-content;
+  }
+  .Todos {
+    flex-direction: row;
+  }
+  .Todos .link:hover {
+    text-decoration: none;
+  }
+}
+
+@media (min-width: 1024px) {
+  .Todos {
+    flex-direction: column;
+  }
+}
+`, css)
+}
+
+func TestVariants(t *testing.T) {
+	r := require.New(t)
+	styles := M{
+		"container": "bg-white dark:bg-gray-900",
+		"row":       "flex group",
+		"button":    "opacity-0 group-hover:opacity-100 focus-visible:ring disabled:opacity-50",
+		"item":      "first:mt-0 last:mb-0 odd:bg-gray-50 even:bg-white visited:text-purple-600 active:text-red-900",
+		"toggle":    "peer",
+		"label":     "text-gray-500 peer-checked:text-green-500 dark:hover:sm:text-white",
+	}
+	r.Equal(`
+.Todo .button {
+  opacity: 0;
+}
+
+.Todo .row:hover .button {
+  opacity: 1;
+}
+
+.Todo .button:focus-visible {
+  box-shadow:  0 0 0 calc(3px + 0px) rgba(59, 130, 246, 0.5);
+}
+
+.Todo .button:disabled {
+  opacity: 0.5;
+}
+
+.Todo {
+  --tw-bg-opacity: 1; background-color: rgba(255, 255, 255, var(--tw-bg-opacity));
+}
+
+.Todo .item {
+}
+
+.Todo .item:first-child {
+  margin-top: 0px;
+}
+
+.Todo .item:last-child {
+  margin-bottom: 0px;
+}
+
+.Todo .item:nth-child(odd) {
+  --tw-bg-opacity: 1; background-color: rgba(249, 250, 251, var(--tw-bg-opacity));
+}
+
+.Todo .item:nth-child(even) {
+  --tw-bg-opacity: 1; background-color: rgba(255, 255, 255, var(--tw-bg-opacity));
+}
+
+.Todo .item:visited {
+  --tw-text-opacity: 1; color: rgba(124, 58, 237, var(--tw-text-opacity));
+}
+
+.Todo .item:active {
+  --tw-text-opacity: 1; color: rgba(127, 29, 29, var(--tw-text-opacity));
+}
+
+.Todo .label {
+  --tw-text-opacity: 1; color: rgba(107, 114, 128, var(--tw-text-opacity));
+}
+
+.Todo .toggle:checked ~ .label {
+  --tw-text-opacity: 1; color: rgba(16, 185, 129, var(--tw-text-opacity));
+}
+
+.Todo .row {
+  display: flex;
+}
+
+.Todo .toggle {
+}
+
+@media (prefers-color-scheme: dark) {
+  .Todo {
+    --tw-bg-opacity: 1; background-color: rgba(17, 24, 39, var(--tw-bg-opacity));
+  }
+}
+
+@media (min-width: 640px) and (prefers-color-scheme: dark) {
+  .Todo .label:hover {
+    --tw-text-opacity: 1; color: rgba(255, 255, 255, var(--tw-text-opacity));
+  }
+}
+`, computeCss(styles, "Todo"))
+
+	DarkMode = "class"
+	defer func() {
+		DarkMode = "media"
+	}()
+	r.Equal(`
+.Todo {
+  --tw-bg-opacity: 1; background-color: rgba(255, 255, 255, var(--tw-bg-opacity));
+}
+
+.dark .Todo {
+  --tw-bg-opacity: 1; background-color: rgba(17, 24, 39, var(--tw-bg-opacity));
+}
+`, computeCss(M{"container": "bg-white dark:bg-gray-900"}, "Todo"))
+}
+
+func TestArbitraryValues(t *testing.T) {
+	r := require.New(t)
+	r.Equal(`
+.Tweet .avatar {
+  background-color: rgba(239, 68, 68, 0.5);
+  border-color: rgba(29, 161, 242, 0.35);
+  color: hsl(200 100% 50%);
+  --tw-bg-opacity: .25;
+}
+
+.Tweet .avatar:hover {
+  background-image: url(https://example.com/a.png);
+}
+
+.Tweet {
+  width: 37rem;
+  --tw-bg-opacity: 1; background-color: rgba(29, 161, 242, var(--tw-bg-opacity));
+  padding-left: 3px;padding-right: 3px;
+  font-size: 14px;
+  border-width: 3px;
+  --tw-text-opacity: 1; color: rgba(127, 29, 29, var(--tw-text-opacity));
+  --tw-text-opacity: 0.2;
+}
+`, computeCss(M{
+		"container": "w-[37rem] text-opacity-20 bg-[#1da1f2] px-[3px] text-[14px] border-[3px] text-red-900",
+		"avatar":    "bg-red-500/50 bg-opacity-[.25] border-[#1da1f2]/[0.35] text-[hsl(200_100%_50%)] hover:bg-[url(https://example.com/a.png)]",
+	}, "Tweet"))
+
+	invalid := MS{
+		"w-[37rem":         "",
+		"bg-red-500/150":   "invalid class 'bg-red-500/150' of 'container': invalid opacity modifier in 'bg-red-500/150': '150' is not between 0 and 100",
+		"w-1/2/3":          "invalid class 'w-1/2/3' of 'container': opacity modifier in 'w-1/2/3' needs a color",
+		"w-[10px]/50":      "invalid class 'w-[10px]/50' of 'container': opacity modifier in 'w-[10px]/50' needs a color",
+		"flex/50":          "invalid class 'flex/50' of 'container': opacity modifier in 'flex/50' needs a color",
+		"bg-[red;color:x]": "invalid class 'bg-[red;color:x]' of 'container': invalid arbitrary value in 'bg-[red;color:x]'",
+		"foo-[1px]":        "invalid class 'foo-[1px]' of 'container': unknown utility for arbitrary value 'foo-[1px]'",
+	}
+	for class, err := range invalid {
+		if err == "" {
+			r.Error(validateStyles(M{"container": class}), class)
+			continue
+		}
+		r.EqualError(validateStyles(M{"nested": M{"container": class}}), err, class)
+	}
+	r.NoError(validateStyles(M{"container": "w-1/2 hover:w-[1/3] bg-white/10 sm:text-[#fff]/[.5]"}))
+	r.PanicsWithError("styles of Tweet: invalid class 'w-[]' of 'container': invalid arbitrary value in 'w-[]'", func() {
+		c := NewContext(context.Background(), nil)
+		c.Set("funcName", "Tweet")
+		c.Styles(M{"container": "w-[]"})
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/md5"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	"reflect"
 	"regexp"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -40,10 +42,13 @@ var (
 	globalStatusComponent StatusComponent = nil
 	baseRouter                            = &mux.Router{}
 	pageRouter                            = &mux.Router{}
+	layouts                               = map[string]func(c *gsx.Context) []*gsx.Tag{}
 )
 
 type StatusComponent func(c *gsx.Context, status int, err error) []*gsx.Tag
 
+type LayoutComponent func(c *gsx.Context) []*gsx.Tag
+
 type File struct {
 	Name        string
 	ContentType string
@@ -68,6 +73,11 @@ func init() {
 			PartsExclude: []string{zerolog.TimestampFieldName},
 		})
 	}
+	gsx.DevMode = !IsCloundRun
+	gsx.Minify = IsCloundRun
+	gsx.StripComments = IsCloundRun
+	gsx.StrictStyles = !IsCloundRun
+	gsx.ValidateProps = Validate
 	gsx.RegisterFunc(GetAssetUrl)
 }
 
@@ -102,9 +112,27 @@ func RespondError(w http.ResponseWriter, r *http.Request, status int, err error)
 		})
 		log.Error().Msg(err.Error() + "\n" + formattedStr)
 	}
+	var templateError *gsx.TemplateError
+	isTemplateError := errors.As(err, &templateError)
+	if isTemplateError {
+		log.Error().Msg(templateError.Error() + "\n" + templateError.Snippet)
+	}
 	c := createCtx(r, "Status")
 	c.Set("funcName", "error")
 	c.Set("error", err.Error())
+	if isTemplateError && !IsCloundRun {
+		c.Set("templateError", templateError)
+		tags := c.Render(`
+			<div style="font-family: monospace; padding: 16px;">
+				<h1 style="color: red;">"Template error in " {templateError.Name}</h1>
+				<p>{templateError.File} ":" {templateError.Line} " template " {templateError.TemplateLine} ":" {templateError.Column}</p>
+				<h2>{templateError.Err.Error}</h2>
+				<pre style="background: #f3f4f6; padding: 16px;">{"\n" + templateError.Snippet}</pre>
+			</div>
+		`)
+		gsx.Write(c, w, tags)
+		return
+	}
 	if r.Header.Get("HX-Request") == "true" || globalStatusComponent == nil {
 		tags := c.Render(`
 			<div style="color: red;">
@@ -115,7 +143,7 @@ func RespondError(w http.ResponseWriter, r *http.Request, status int, err error)
 		return
 	}
 	tags := globalStatusComponent(c, status, err)
-	gsx.Write(c, w, tags)
+	gsx.Write(c, w, gsx.RenderLayouts(c, tags, getLayouts(r.URL.Path)))
 }
 
 func PerformRequest(route string, h interface{}, c interface{}, w http.ResponseWriter, r *http.Request, isJson bool) {
@@ -211,6 +239,18 @@ func PerformRequest(route string, h interface{}, c interface{}, w http.ResponseW
 		RespondError(w, r, responseStatus, eris.Wrap(responseError.(error), "Render failed"))
 		return
 	}
+	// the layouts are rendered before the headers are written so that their errors and htmx headers are
+	// part of the response.
+	var tags []*gsx.Tag
+	if page, ok := response.([]*gsx.Tag); ok && !isJson && responseStatus != 204 {
+		tags = gsx.RenderLayouts(c.(*gsx.Context), page, getLayouts(r.URL.Path))
+	}
+	if ctx, ok := c.(*gsx.Context); ok {
+		if err := ctx.WriteHX(w.Header()); err != nil {
+			RespondError(w, r, 500, err)
+			return
+		}
+	}
 	if file, ok := response.(*File); ok {
 		w.Header().Set("Content-Type", file.ContentType)
 		w.Header().Set("Content-Length", fmt.Sprintf("%d", file.Data.Len()))
@@ -233,7 +273,7 @@ func PerformRequest(route string, h interface{}, c interface{}, w http.ResponseW
 	// This has to be at end always
 	w.WriteHeader(responseStatus)
 	if responseStatus != 204 {
-		gsx.Write(c.(*gsx.Context), w, response.([]*gsx.Tag))
+		gsx.Write(c.(*gsx.Context), w, tags)
 	}
 }
 
@@ -249,9 +289,18 @@ func LogMiddleware(next http.Handler) http.Handler {
 		}
 		ua := useragent.Parse(r.UserAgent()).Name
 		defer func() {
-			if err := recover(); err != nil {
-				log.Error().Msgf("%s 599 %s %s", r.Method, ua, url)
-				RespondError(w, r, 599, eris.Errorf("%+v", err))
+			if v := recover(); v != nil {
+				err, ok := v.(error)
+				if !ok {
+					err = eris.Errorf("%+v", v)
+				}
+				status := 599
+				var templateError *gsx.TemplateError
+				if errors.As(err, &templateError) {
+					status = 500
+				}
+				log.Error().Msgf("%s %d %s %s", r.Method, status, ua, url)
+				RespondError(w, r, status, err)
 			}
 		}()
 		m := httpsnoop.CaptureMetrics(next, w, r)
@@ -331,7 +380,7 @@ func RegisterStatusHandler(router *mux.Router, comp StatusComponent) {
 	globalStatusComponent = comp
 	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c := createCtx(r, "Status")
-		tags := comp(c, 404, nil)
+		tags := gsx.RenderLayouts(c, comp(c, 404, nil), getLayouts(r.URL.Path))
 		w.Header().Set("Content-Type", "text/html")
 		w.WriteHeader(404)
 		gsx.Write(c, w, tags)
@@ -349,6 +398,29 @@ func PageRoute(route string, page, action interface{}) {
 	}).Methods("GET", "POST")
 }
 
+// Layout registers a layout for the pages under the prefix like "/" or "/admin/*", the page is rendered as
+// the children of the layout and the layouts of longer prefixes are rendered inside the shorter ones.
+func Layout(prefix string, layout LayoutComponent) {
+	layouts[strings.TrimSuffix(strings.TrimSuffix(prefix, "*"), "/")] = layout
+}
+
+func getLayouts(path string) []func(c *gsx.Context) []*gsx.Tag {
+	prefixes := []string{}
+	for prefix := range layouts {
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) < len(prefixes[j])
+	})
+	result := []func(c *gsx.Context) []*gsx.Tag{}
+	for _, prefix := range prefixes {
+		result = append(result, layouts[prefix])
+	}
+	return result
+}
+
 func GetUrl(ctx context.Context) *url.URL {
 	return ctx.Value("url").(*url.URL)
 }
@@ -405,6 +477,11 @@ func GetRouter() *mux.Router {
 }
 
 func Run(port string) {
+	if gsx.StrictStyles {
+		for _, w := range gsx.StyleWarnings() {
+			log.Warn().Msg(w)
+		}
+	}
 	log.Info().Msg("http server listening on http://localhost:" + port)
 	srv := server.New(baseRouter, nil)
 	if err := srv.ListenAndServe(":" + port); err != nil {
@@ -0,0 +1,202 @@
+package gromer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pyros2097/gromer/gsx"
+	"github.com/rotisserie/eris"
+	"github.com/rs/zerolog/log"
+)
+
+// SSEHeartbeat is the interval of the comments sent to keep the event streams open through proxies.
+var SSEHeartbeat = 15 * time.Second
+
+// SSEHandler sends the events of the stream till it returns or the client disconnects.
+type SSEHandler func(c *gsx.Context, s *EventStream) error
+
+// EventStream sends server sent events to a client, the data of the events can be gsx tags which are
+// swapped by the htmx sse extension with sse-swap="name". It is safe to use from many goroutines.
+type EventStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	w      io.Writer
+}
+
+// Done is closed when the client disconnects or the handler returns.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Send renders the tags and sends them as the data of the event.
+func (s *EventStream) Send(event string, tags []*gsx.Tag) error {
+	buf := &bytes.Buffer{}
+	gsx.WriteFragment(buf, tags)
+	return s.SendString(event, buf.String())
+}
+
+// SendString sends the data as is, an empty event is sent as the default message event.
+func (s *EventStream) SendString(event, data string) error {
+	if strings.ContainsAny(event, "\r\n") {
+		return eris.Errorf("sse event name '%s' can't have newlines", event)
+	}
+	b := strings.Builder{}
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+func (s *EventStream) write(v string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(s.w, v); err != nil {
+		return err
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// close cancels the stream after the write in progress so that the response writer is not used after
+// the handler returns.
+func (s *EventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancel()
+}
+
+func (s *EventStream) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.write(": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// SSERoute registers a route which streams server sent events, the handler gets the event stream and
+// should return when the stream is done.
+//
+//	gromer.SSERoute("/todos/events", func(c *gsx.Context, s *gromer.EventStream) error {
+//		return todosHub.Subscribe(s)
+//	})
+func SSERoute(route string, handler SSEHandler) {
+	pageRouter.HandleFunc(route, sseHandler(route, handler)).Methods("GET")
+}
+
+func sseHandler(route string, handler SSEHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			RespondError(w, r, 500, eris.New("streaming is not supported by the response writer"))
+			return
+		}
+		ctx, cancel := context.WithCancel(r.Context())
+		c := createCtx(r.WithContext(ctx), route)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		s := &EventStream{ctx: ctx, cancel: cancel, w: w}
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.heartbeat(SSEHeartbeat)
+		}()
+		defer func() {
+			s.close()
+			wg.Wait()
+		}()
+		if err := handler(c, s); err != nil && ctx.Err() == nil {
+			log.Error().Msgf("sse %s: %s", route, err.Error())
+		}
+	}
+}
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+// Hub broadcasts events to the event streams subscribed to it.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[chan sseEvent]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[chan sseEvent]struct{}{}}
+}
+
+// Broadcast renders the tags once and sends the event to all the subscribers, a subscriber which is too
+// slow to receive the events misses them instead of blocking the others.
+func (h *Hub) Broadcast(event string, tags []*gsx.Tag) {
+	buf := &bytes.Buffer{}
+	gsx.WriteFragment(buf, tags)
+	h.BroadcastString(event, buf.String())
+}
+
+// BroadcastString sends the event with the data as is to all the subscribers.
+func (h *Hub) BroadcastString(event, data string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- sseEvent{event, data}:
+		default:
+		}
+	}
+}
+
+// Subscribe sends the broadcasted events to the stream till the client disconnects.
+func (h *Hub) Subscribe(s *EventStream) error {
+	ch := make(chan sseEvent, 16)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.subscribers, ch)
+		h.mu.Unlock()
+	}()
+	for {
+		select {
+		case <-s.Done():
+			return nil
+		case e := <-ch:
+			if err := s.SendString(e.name, e.data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Len returns the number of subscribers.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
@@ -0,0 +1,81 @@
+package gromer
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyros2097/gromer/gsx"
+	"github.com/stretchr/testify/assert"
+)
+
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	lines := []string{}
+	for {
+		line, err := r.ReadString('\n')
+		assert.NoError(t, err)
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestSSERoute(t *testing.T) {
+	SSEHeartbeat = 50 * time.Millisecond
+	defer func() {
+		SSEHeartbeat = 15 * time.Second
+	}()
+	hub := NewHub()
+	server := httptest.NewServer(sseHandler("/todos/events", func(c *gsx.Context, s *EventStream) error {
+		c.Set("count", 2)
+		if err := s.Send("count", c.Render(`<span id="todo-count">{count} items</span>`)); err != nil {
+			return err
+		}
+		return hub.Subscribe(s)
+	}))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	r := bufio.NewReader(res.Body)
+	assert.Equal(t, "event: count\ndata: <span id=\"todo-count\">\ndata:   2 items\ndata: </span>\n", readEvent(t, r))
+
+	assert.Eventually(t, func() bool { return hub.Len() == 1 }, time.Second, 10*time.Millisecond)
+	hub.BroadcastString("", "one\ntwo")
+	assert.Equal(t, "data: one\ndata: two\n", readEvent(t, r))
+	assert.Equal(t, ": heartbeat\n", readEvent(t, r))
+
+	res.Body.Close()
+	assert.Eventually(t, func() bool {
+		hub.BroadcastString("count", "3")
+		return hub.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSSEHandlerReturn(t *testing.T) {
+	SSEHeartbeat = time.Millisecond
+	defer func() {
+		SSEHeartbeat = 15 * time.Second
+	}()
+	sent := make(chan error)
+	handler := sseHandler("/todos/events", func(c *gsx.Context, s *EventStream) error {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			sent <- s.SendString("count", "3")
+		}()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/todos/events", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, ": heartbeat\n\n")
+	assert.Error(t, <-sent)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, body, w.Body.String())
+}